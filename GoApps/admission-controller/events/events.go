@@ -2,8 +2,11 @@ package events
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
 	"admission-controller/config"
 	"admission-controller/controller"
@@ -31,7 +34,7 @@ func SubscribeToAdmissionRate(rdb *redis.Client) {
 	for msg := range ch {
 		// Update the admission rate whenever a new message is received
 		admissionRateStr := msg.Payload
-		admissionRate, err := strconv.ParseFloat(admissionRateStr, 64)
+		admissionRate, err := parseAdmissionRate(admissionRateStr)
 		if err != nil {
 			log.Printf("⚠️ Error parsing admission rate: %v", err)
 			continue
@@ -48,6 +51,22 @@ func SubscribeToAdmissionRate(rdb *redis.Client) {
 	}
 }
 
+// Helper function to parse an admission rate update. The load balancer publishes JSON
+// with an explicit unit, older versions published a bare number.
+func parseAdmissionRate(payload string) (float64, error) {
+	var message struct {
+		Rate *float64 `json:"rate"`
+		Unit string   `json:"unit"`
+	}
+	if err := json.Unmarshal([]byte(payload), &message); err == nil && message.Rate != nil {
+		if message.Unit != "" && message.Unit != "events/s" {
+			return 0, fmt.Errorf("unsupported admission rate unit %q", message.Unit)
+		}
+		return *message.Rate, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(payload), 64)
+}
+
 // Initialize the rate controller
 func InitRateController(alpha, beta float64) {
 	rateController = controller.NewRateController(alpha, beta)
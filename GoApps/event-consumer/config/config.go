@@ -16,6 +16,8 @@ var (
 	NumWorkers            int
 	RequestLoggingEnabled bool
 	ServiceName           string
+	DeadlineExtension     string
+	TTLExtension          string
 )
 
 func LoadConfig() {
@@ -37,6 +39,16 @@ func LoadConfig() {
 		log.Println("⚠️ Service Name Environmental Var is not declared.")
 	}
 
+	// Event deadlines, given as an absolute time or as a TTL relative to the time attribute
+	DeadlineExtension = os.Getenv("DEADLINE_EXTENSION")
+	if DeadlineExtension == "" {
+		DeadlineExtension = "deadline"
+	}
+	TTLExtension = os.Getenv("TTL_EXTENSION")
+	if TTLExtension == "" {
+		TTLExtension = "ttl"
+	}
+
 	RequestLoggingEnabled, _ = strconv.ParseBool(os.Getenv("REQUEST_LOGGING_ENABLED"))
 	if RequestLoggingEnabled {
 		log.Println("🔍 Request logging enabled, request logging is not recommended for production since it might log sensitive information")
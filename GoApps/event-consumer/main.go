@@ -9,8 +9,10 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"consumer/config"
+	"consumer/deadline"
 	"consumer/logging"
 	"consumer/metrics"
 
@@ -93,6 +95,12 @@ func startProcessor(workerID int, wg *sync.WaitGroup) {
 	}
 
 	for event := range config.RequestQueue {
+		// Skip images that went stale while waiting in the queue
+		if deadline.Expired(event, time.Now()) {
+			log.Printf("⌛ Worker %d: event %s expired, skipping it", workerID, event.ID())
+			metrics.IncEventsExpired("worker")
+			continue
+		}
 		processImage(event, yolonet)
 	}
 }
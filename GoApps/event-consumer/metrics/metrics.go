@@ -18,6 +18,11 @@ var (
 		Name: "queued_requests",
 		Help: "Number of requests weighting to be processed in internal queue.",
 	}, []string{"service"})
+
+	EventsExpired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_expired_total",
+		Help: "Number of events discarded because their deadline passed, by stage.",
+	}, []string{"service", "stage"})
 )
 
 func init() {
@@ -50,3 +55,7 @@ func UpdateMetric(value float64) {
 	QueuedRequests.WithLabelValues(serviceName).Set(value)
 	log.Printf("Updated QueuedRequests for %s to %f", serviceName, value)
 }
+
+func IncEventsExpired(stage string) {
+	EventsExpired.WithLabelValues(serviceName, stage).Inc()
+}
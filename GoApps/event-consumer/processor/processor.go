@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/wimspaargaren/yolov3"
 	"gocv.io/x/gocv"
 	"consumer/config"
+	"consumer/deadline"
+	"consumer/metrics"
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 )
 
@@ -26,6 +29,12 @@ func StartProcessor(workerID int, wg *sync.WaitGroup) {
 	}
 
 	for event := range config.RequestQueue {
+		// Skip images that went stale while waiting in the queue
+		if deadline.Expired(event, time.Now()) {
+			log.Printf("⌛ Worker %d: event %s expired, skipping it", workerID, event.ID())
+			metrics.IncEventsExpired("worker")
+			continue
+		}
 		processImage(event, yolonet)
 	}
 }
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"load-balancer/config"
+	"load-balancer/routing"
+	"load-balancer/rules"
+)
+
+type routingStatus struct {
+	Algorithm string   `json:"algorithm"`
+	Available []string `json:"available"`
+}
+
+type routingRequest struct {
+	Algorithm string `json:"algorithm"`
+}
+
+// StartAdminServer serves the admin API used to inspect and reconfigure the load balancer at runtime
+func StartAdminServer() {
+	mux := http.NewServeMux()
+	server := &http.Server{
+		Addr:    ":" + config.AdminPort,
+		Handler: mux,
+	}
+	mux.HandleFunc("/routing", handleRouting)
+	mux.HandleFunc("/breakers", handleBreakers)
+	mux.HandleFunc("/rules", handleRules)
+	log.Printf("🛠️ admin: listening on port %s", config.AdminPort)
+	log.Fatal(server.ListenAndServe())
+}
+
+// handleRouting reports the active routing algorithm on GET and switches it on PUT/POST,
+// taking the new name from an {"algorithm": "..."} body or the ?algorithm= query parameter.
+func handleRouting(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		request := routingRequest{Algorithm: r.URL.Query().Get("algorithm")}
+		if request.Algorithm == "" {
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := routing.SetAlgorithm(request.Algorithm); err != nil {
+			log.Printf("⚠️ Rejected routing algorithm switch: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, _ := routing.SelectedAlgorithm()
+	writeJSON(w, routingStatus{Algorithm: name, Available: routing.Algorithms()})
+}
+
+// handleBreakers lists the circuit breaker of every service on GET and force-closes the
+// breaker named by ?service= on POST.
+func handleBreakers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		service := r.URL.Query().Get("service")
+		if err := routing.ResetBreaker(service); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("🔧 Circuit breaker for %s reset through admin API", service)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, routing.Breakers())
+}
+
+// handleRules lists the active routing rules on GET and reloads them from RULES_FILE on
+// POST. A file that fails validation is rejected and the previous rules stay active.
+func handleRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := rules.Reload(); err != nil {
+			log.Printf("⚠️ Rejected routing rules reload: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, rules.Rules())
+}
+
+// Helper function to write a JSON response body
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("❌ Failed to write admin response: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+// Command replay sends dead-lettered events from an NDJSON file written by the file
+// sink back into the load balancer.
+//
+//	replay -file deadletter.ndjson -target http://load-balancer.rabbitmq-setup.svc.cluster.local
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"load-balancer/deadletter"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+func main() {
+	file := flag.String("file", "deadletter.ndjson", "NDJSON file written by the dead-letter file sink")
+	target := flag.String("target", "http://localhost:8080", "URL of the load balancer's event receiver")
+	stage := flag.String("stage", "", "Only replay events dead-lettered by this stage")
+	interval := flag.Duration("interval", 0, "Pause between two replayed events")
+	dryRun := flag.Bool("dry-run", false, "List the events without sending them")
+	flag.Parse()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("❌ Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	c, err := cloudevents.NewClientHTTP(cloudevents.WithTarget(*target))
+	if err != nil {
+		log.Fatalf("❌ Failed to create client: %v", err)
+	}
+
+	replayed, failed, skipped := 0, 0, 0
+	err = deadletter.ReadRecords(f, func(record deadletter.Record) error {
+		if *stage != "" && record.Stage != *stage {
+			skipped++
+			return nil
+		}
+		if *dryRun {
+			log.Printf("📋 %s from %s (destination=%s, attempts=%d): %s",
+				record.Event.ID(), record.Stage, record.Destination, record.Attempts, record.LastError)
+			replayed++
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if result := c.Send(ctx, record.Event); !cloudevents.IsACK(result) {
+			log.Printf("❌ Failed to replay event %s: %v", record.Event.ID(), result)
+			failed++
+		} else {
+			log.Printf("✅ Replayed event %s", record.Event.ID())
+			replayed++
+		}
+		time.Sleep(*interval)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to read %s: %v", *file, err)
+	}
+
+	log.Printf("📊 Replayed %d event(s), %d failed, %d skipped", replayed, failed, skipped)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
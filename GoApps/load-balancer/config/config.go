@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,15 +21,100 @@ var (
 	AdmissionRateInterval time.Duration
 	NumServices           int
 	RoutingAlgorithm      string
+	QdReqsRefreshInterval time.Duration
+	FallbackAlgorithm     string
+	AffinityExtension     string
+	HashRingSize          int
+	AdminPort             string
+	IngressMode           string
+	QueueMonitor          string
+	RabbitMQManagementURL string
+	AMQPPrefetch          int
+	AMQPRequeueDelay      time.Duration
+	AMQPRequeueMaxDelay   time.Duration
+	AMQPMaxRedeliveries   int
+	RulesFile             string
+	PeakEWMADecay         time.Duration
+	NackFailures          = make(map[string]bool)
+	RetryMaxAttempts      int
+	RetryBackoff          time.Duration
+	RetryMaxBackoff       time.Duration
+	RetryBudgetRatio      float64
+	RetryBudgetReserve    int
 	MaxAdmissionRate      int
 	MinAdmissionRate      int
+	AdmissionRateMaxStep  float64
+	EpochTimeout          time.Duration
+	TotalThroughput       float64
+	Normalizer            string
+	NormalizerMinHeadroom float64
+	IngressRateDecay      time.Duration
+
+	// Per-service congestion signals that start a new AIMD epoch for that service only
+	CongestionQueuedRequests int
+	CongestionCooldown       time.Duration
+
+	// Asynchronous dispatch queue between the receiver and the routing algorithm
+	DispatchWorkers   int
+	DispatchQueueSize int
+	DispatchOverflow  string
+	PriorityWeights   map[string]int
+	PriorityExtension string
+	DefaultPriority   string
+	DeadlineExtension string
+	TTLExtension      string
+
+	// Transport settings of the pooled dispatch clients
+	DispatchTimeout             time.Duration
+	DispatchIdleConnTimeout     time.Duration
+	DispatchMaxIdleConnsPerHost int
+	DispatchDisableKeepAlives   bool
+	DispatchH2C                 bool
+
+	// De-duplication of events redelivered by the broker
+	DedupBackend string
+	DedupTTL     time.Duration
+	DedupLRUSize int
+
+	// Dead-letter sink receiving events that could not be delivered
+	DeadLetterSink     string
+	DeadLetterFile     string
+	DeadLetterAMQPURL  string
+	DeadLetterExchange string
+	DeadLetterURL      string
+
+	// Circuit breaker parameters, shared by all services
+	BreakerConsecutiveFailures int
+	BreakerErrorRate           float64
+	BreakerMinRequests         int
+	BreakerWindow              time.Duration
+	BreakerOpenDuration        time.Duration
+	BreakerHalfOpenProbes      int
 
 	// Maps for service-specific parameters
+	ServiceNames         = make(map[int]string)
+	ServiceURLs          = make(map[int]string)
+	ServicePaths         = make(map[int]string)
+	ServiceHeaders       = make(map[int]map[string]string)
+	ServiceConsumers     = make(map[int]string)
 	InitialCurrWeights   = make(map[int]float64)
 	InitialEmptyQWeights = make(map[int]float64)
 	RawAdmissionRates    = make(map[int]float64)
+	MinAdmissionRates    = make(map[int]float64)
+	MaxAdmissionRates    = make(map[int]float64)
 	Alphas               = make(map[int]int)
 	Betas                = make(map[int]float64)
+
+	// Control law of every service and the parameters of the non-AIMD laws
+	ControlLaws  = make(map[int]string)
+	CubicCs      = make(map[int]float64)
+	PIDSetpoints = make(map[int]float64)
+	PIDKps       = make(map[int]float64)
+	PIDKis       = make(map[int]float64)
+	PIDKds       = make(map[int]float64)
+	VegasAlphas  = make(map[int]float64)
+	VegasBetas   = make(map[int]float64)
+	VegasSteps   = make(map[int]float64)
 )
 
 func LoadConfig() {
@@ -86,6 +173,450 @@ func LoadConfig() {
 		RoutingAlgorithm = "AIMD"
 	}
 
+	AdminPort = os.Getenv("ADMIN_PORT")
+	if AdminPort == "" {
+		AdminPort = "9096"
+	}
+
+	// Where events come from: the HTTP receiver fed by the Knative trigger, or the
+	// trigger queue consumed directly over AMQP
+	IngressMode = os.Getenv("INGRESS_MODE")
+	switch IngressMode {
+	case "":
+		IngressMode = "http"
+	case "http", "amqp":
+	default:
+		log.Fatalf("❌ Invalid INGRESS_MODE value: %s", IngressMode)
+	}
+
+	AMQPPrefetch = 10
+	if prefetchStr := os.Getenv("AMQP_PREFETCH"); prefetchStr != "" {
+		prefetch, err := strconv.Atoi(prefetchStr)
+		if err != nil || prefetch < 1 {
+			log.Printf("⚠️ Invalid value for AMQP_PREFETCH: %s. Using default: 10", prefetchStr)
+		} else {
+			AMQPPrefetch = prefetch
+		}
+	}
+
+	// NACKed messages wait in a retry queue before they are requeued, doubling the delay with
+	// every redelivery, and given up after AMQP_MAX_REDELIVERIES (0 = never)
+	AMQPRequeueDelay = time.Second
+	if delayStr := os.Getenv("AMQP_REQUEUE_DELAY"); delayStr != "" {
+		delay, err := strconv.Atoi(delayStr)
+		if err != nil || delay < 0 {
+			log.Printf("⚠️ Invalid value for AMQP_REQUEUE_DELAY: %s. Using default: 1000ms", delayStr)
+		} else {
+			AMQPRequeueDelay = time.Duration(delay) * time.Millisecond
+		}
+	}
+
+	AMQPRequeueMaxDelay = 30 * time.Second
+	if maxDelayStr := os.Getenv("AMQP_REQUEUE_MAX_DELAY"); maxDelayStr != "" {
+		maxDelay, err := strconv.Atoi(maxDelayStr)
+		if err != nil || maxDelay < 0 {
+			log.Printf("⚠️ Invalid value for AMQP_REQUEUE_MAX_DELAY: %s. Using default: 30000ms", maxDelayStr)
+		} else {
+			AMQPRequeueMaxDelay = time.Duration(maxDelay) * time.Millisecond
+		}
+	}
+
+	AMQPMaxRedeliveries = 5
+	if maxRedeliveriesStr := os.Getenv("AMQP_MAX_REDELIVERIES"); maxRedeliveriesStr != "" {
+		maxRedeliveries, err := strconv.Atoi(maxRedeliveriesStr)
+		if err != nil || maxRedeliveries < 0 {
+			log.Printf("⚠️ Invalid value for AMQP_MAX_REDELIVERIES: %s. Using default: 5", maxRedeliveriesStr)
+		} else {
+			AMQPMaxRedeliveries = maxRedeliveries
+		}
+	}
+	log.Printf("📋 Ingress Config: mode=%s, prefetch=%d, requeue delay=%v (max %v), max redeliveries=%d",
+		IngressMode, AMQPPrefetch, AMQPRequeueDelay, AMQPRequeueMaxDelay, AMQPMaxRedeliveries)
+
+	// Source of the trigger queue state driving the empty queue events
+	QueueMonitor = os.Getenv("QUEUE_MONITOR")
+	switch QueueMonitor {
+	case "":
+		QueueMonitor = "amqp"
+	case "amqp", "management":
+	default:
+		log.Fatalf("❌ Invalid QUEUE_MONITOR value: %s", QueueMonitor)
+	}
+
+	RabbitMQManagementURL = os.Getenv("RABBITMQ_MANAGEMENT_URL")
+	if RabbitMQManagementURL == "" {
+		RabbitMQManagementURL = "http://rabbitmq.rabbitmq-setup.svc.cluster.local:15672"
+	}
+
+	// Failure classes that are NACKed to the broker for redelivery, everything else is dropped
+	nackFailuresStr := os.Getenv("NACK_FAILURES")
+	if nackFailuresStr == "" {
+		nackFailuresStr = "timeout,network,429,5xx,no-destination"
+	}
+	NackFailures = make(map[string]bool)
+	for _, class := range strings.Split(nackFailuresStr, ",") {
+		class = strings.TrimSpace(class)
+		switch class {
+		case "":
+		case "timeout", "network", "429", "4xx", "5xx", "no-destination":
+			NackFailures[class] = true
+		default:
+			log.Printf("⚠️ Ignoring unknown failure class in NACK_FAILURES: %s", class)
+		}
+	}
+	log.Printf("📋 Failures NACKed to the broker: %v", NackFailures)
+
+	// Retry and failover of failed dispatches
+	RetryMaxAttempts = 3
+	if retryMaxAttemptsStr := os.Getenv("RETRY_MAX_ATTEMPTS"); retryMaxAttemptsStr != "" {
+		retryMaxAttempts, err := strconv.Atoi(retryMaxAttemptsStr)
+		if err != nil || retryMaxAttempts < 1 {
+			log.Printf("⚠️ Invalid value for RETRY_MAX_ATTEMPTS: %s. Using default: 3", retryMaxAttemptsStr)
+		} else {
+			RetryMaxAttempts = retryMaxAttempts
+		}
+	}
+
+	RetryBackoff = 100 * time.Millisecond
+	if retryBackoffStr := os.Getenv("RETRY_BACKOFF"); retryBackoffStr != "" {
+		retryBackoff, err := strconv.Atoi(retryBackoffStr)
+		if err != nil || retryBackoff < 0 {
+			log.Printf("⚠️ Invalid value for RETRY_BACKOFF: %s. Using default: 100ms", retryBackoffStr)
+		} else {
+			RetryBackoff = time.Duration(retryBackoff) * time.Millisecond
+		}
+	}
+
+	RetryMaxBackoff = 2 * time.Second
+	if retryMaxBackoffStr := os.Getenv("RETRY_MAX_BACKOFF"); retryMaxBackoffStr != "" {
+		retryMaxBackoff, err := strconv.Atoi(retryMaxBackoffStr)
+		if err != nil || retryMaxBackoff < 0 {
+			log.Printf("⚠️ Invalid value for RETRY_MAX_BACKOFF: %s. Using default: 2000ms", retryMaxBackoffStr)
+		} else {
+			RetryMaxBackoff = time.Duration(retryMaxBackoff) * time.Millisecond
+		}
+	}
+
+	RetryBudgetRatio = 0.2
+	if retryBudgetRatioStr := os.Getenv("RETRY_BUDGET_RATIO"); retryBudgetRatioStr != "" {
+		retryBudgetRatio, err := strconv.ParseFloat(retryBudgetRatioStr, 64)
+		if err != nil || retryBudgetRatio < 0 {
+			log.Printf("⚠️ Invalid value for RETRY_BUDGET_RATIO: %s. Using default: 0.2", retryBudgetRatioStr)
+		} else {
+			RetryBudgetRatio = retryBudgetRatio
+		}
+	}
+
+	RetryBudgetReserve = 10
+	if retryBudgetReserveStr := os.Getenv("RETRY_BUDGET_RESERVE"); retryBudgetReserveStr != "" {
+		retryBudgetReserve, err := strconv.Atoi(retryBudgetReserveStr)
+		if err != nil || retryBudgetReserve < 0 {
+			log.Printf("⚠️ Invalid value for RETRY_BUDGET_RESERVE: %s. Using default: 10", retryBudgetReserveStr)
+		} else {
+			RetryBudgetReserve = retryBudgetReserve
+		}
+	}
+	log.Printf("📋 Retry Config: attempts=%d, backoff=%v..%v, budget ratio=%.2f, reserve=%d",
+		RetryMaxAttempts, RetryBackoff, RetryMaxBackoff, RetryBudgetRatio, RetryBudgetReserve)
+
+	// Dispatch worker pool, disabled (synchronous routing) unless DISPATCH_WORKERS > 0
+	DispatchWorkers = 0
+	if workersStr := os.Getenv("DISPATCH_WORKERS"); workersStr != "" {
+		workers, err := strconv.Atoi(workersStr)
+		if err != nil || workers < 0 {
+			log.Printf("⚠️ Invalid value for DISPATCH_WORKERS: %s. Using default: 0", workersStr)
+		} else {
+			DispatchWorkers = workers
+		}
+	}
+
+	// Workers ACK events to the broker as soon as they are queued, so a failure during
+	// dispatch can only be dead-lettered. An explicit NACK_FAILURES cannot be honored and
+	// is rejected, otherwise every failure class is dropped.
+	if DispatchWorkers > 0 && IngressMode == "http" {
+		if value, ok := os.LookupEnv("NACK_FAILURES"); ok && strings.TrimSpace(value) != "" {
+			log.Fatalf("❌ NACK_FAILURES=%s cannot be combined with DISPATCH_WORKERS=%d, queued events are already ACKed. Unset NACK_FAILURES or DISPATCH_WORKERS", value, DispatchWorkers)
+		}
+		NackFailures = make(map[string]bool)
+		log.Println("📋 Dispatch workers enabled, failed events are dead-lettered instead of NACKed")
+	}
+
+	DispatchQueueSize = 1000
+	if queueSizeStr := os.Getenv("DISPATCH_QUEUE_SIZE"); queueSizeStr != "" {
+		queueSize, err := strconv.Atoi(queueSizeStr)
+		if err != nil || queueSize < 1 {
+			log.Printf("⚠️ Invalid value for DISPATCH_QUEUE_SIZE: %s. Using default: 1000", queueSizeStr)
+		} else {
+			DispatchQueueSize = queueSize
+		}
+	}
+
+	DispatchOverflow = os.Getenv("DISPATCH_OVERFLOW")
+	switch DispatchOverflow {
+	case "reject", "block":
+	case "":
+		DispatchOverflow = "reject"
+	default:
+		log.Printf("⚠️ Invalid value for DISPATCH_OVERFLOW: %s. Using default: reject", DispatchOverflow)
+		DispatchOverflow = "reject"
+	}
+
+	// Shares of the workers each priority gets while several queues hold events, so that
+	// sustained high priority traffic slows lower priorities down without starving them
+	PriorityWeights = map[string]int{"high": 8, "normal": 4, "low": 1}
+	if weightsStr := os.Getenv("DISPATCH_PRIORITY_WEIGHTS"); weightsStr != "" {
+		weights, err := parsePriorityWeights(weightsStr)
+		if err != nil {
+			log.Printf("⚠️ Invalid value for DISPATCH_PRIORITY_WEIGHTS: %s (%v). Using default: high=8,normal=4,low=1", weightsStr, err)
+		} else {
+			for priority, weight := range weights {
+				PriorityWeights[priority] = weight
+			}
+		}
+	}
+
+	// Priority classes, events without a valid priority extension get DEFAULT_PRIORITY
+	PriorityExtension = os.Getenv("PRIORITY_EXTENSION")
+	if PriorityExtension == "" {
+		PriorityExtension = "priority"
+	}
+
+	DefaultPriority = os.Getenv("DEFAULT_PRIORITY")
+	switch DefaultPriority {
+	case "high", "normal", "low":
+	case "":
+		DefaultPriority = "normal"
+	default:
+		log.Printf("⚠️ Invalid value for DEFAULT_PRIORITY: %s. Using default: normal", DefaultPriority)
+		DefaultPriority = "normal"
+	}
+	// Event deadlines, given as an absolute time or as a TTL relative to the time attribute
+	DeadlineExtension = os.Getenv("DEADLINE_EXTENSION")
+	if DeadlineExtension == "" {
+		DeadlineExtension = "deadline"
+	}
+
+	TTLExtension = os.Getenv("TTL_EXTENSION")
+	if TTLExtension == "" {
+		TTLExtension = "ttl"
+	}
+
+	log.Printf("📋 Dispatch Queue Config: workers=%d, size=%d per priority, overflow=%s, default priority=%s, weights=high:%d/normal:%d/low:%d",
+		DispatchWorkers, DispatchQueueSize, DispatchOverflow, DefaultPriority,
+		PriorityWeights["high"], PriorityWeights["normal"], PriorityWeights["low"])
+
+	// Pooled dispatch clients
+	DispatchTimeout = 10 * time.Second
+	if timeoutStr := os.Getenv("DISPATCH_TIMEOUT"); timeoutStr != "" {
+		timeout, err := strconv.Atoi(timeoutStr)
+		if err != nil || timeout <= 0 {
+			log.Printf("⚠️ Invalid value for DISPATCH_TIMEOUT: %s. Using default: 10000ms", timeoutStr)
+		} else {
+			DispatchTimeout = time.Duration(timeout) * time.Millisecond
+		}
+	}
+
+	DispatchIdleConnTimeout = 90 * time.Second
+	if idleTimeoutStr := os.Getenv("DISPATCH_IDLE_CONN_TIMEOUT"); idleTimeoutStr != "" {
+		idleTimeout, err := strconv.Atoi(idleTimeoutStr)
+		if err != nil || idleTimeout <= 0 {
+			log.Printf("⚠️ Invalid value for DISPATCH_IDLE_CONN_TIMEOUT: %s. Using default: 90000ms", idleTimeoutStr)
+		} else {
+			DispatchIdleConnTimeout = time.Duration(idleTimeout) * time.Millisecond
+		}
+	}
+
+	DispatchMaxIdleConnsPerHost = 100
+	if maxIdleStr := os.Getenv("DISPATCH_MAX_IDLE_CONNS_PER_HOST"); maxIdleStr != "" {
+		maxIdle, err := strconv.Atoi(maxIdleStr)
+		if err != nil || maxIdle < 1 {
+			log.Printf("⚠️ Invalid value for DISPATCH_MAX_IDLE_CONNS_PER_HOST: %s. Using default: 100", maxIdleStr)
+		} else {
+			DispatchMaxIdleConnsPerHost = maxIdle
+		}
+	}
+
+	DispatchDisableKeepAlives, _ = strconv.ParseBool(os.Getenv("DISPATCH_DISABLE_KEEP_ALIVES"))
+	DispatchH2C, _ = strconv.ParseBool(os.Getenv("DISPATCH_H2C"))
+	log.Printf("📋 Dispatch Config: timeout=%v, idle timeout=%v, max idle per host=%d, keep-alive=%t, h2c=%t",
+		DispatchTimeout, DispatchIdleConnTimeout, DispatchMaxIdleConnsPerHost, !DispatchDisableKeepAlives, DispatchH2C)
+
+	// De-duplication stage, disabled unless DEDUP_BACKEND is set
+	DedupBackend = os.Getenv("DEDUP_BACKEND")
+	switch DedupBackend {
+	case "":
+		DedupBackend = "none"
+	case "none", "redis", "memory":
+	default:
+		log.Fatalf("❌ Invalid DEDUP_BACKEND value: %s", DedupBackend)
+	}
+
+	DedupTTL = 10 * time.Minute
+	if dedupTTLStr := os.Getenv("DEDUP_TTL"); dedupTTLStr != "" {
+		dedupTTL, err := strconv.Atoi(dedupTTLStr)
+		if err != nil || dedupTTL <= 0 {
+			log.Printf("⚠️ Invalid value for DEDUP_TTL: %s. Using default: 600000ms", dedupTTLStr)
+		} else {
+			DedupTTL = time.Duration(dedupTTL) * time.Millisecond
+		}
+	}
+
+	DedupLRUSize = 10000
+	if lruSizeStr := os.Getenv("DEDUP_LRU_SIZE"); lruSizeStr != "" {
+		lruSize, err := strconv.Atoi(lruSizeStr)
+		if err != nil || lruSize < 1 {
+			log.Printf("⚠️ Invalid value for DEDUP_LRU_SIZE: %s. Using default: 10000", lruSizeStr)
+		} else {
+			DedupLRUSize = lruSize
+		}
+	}
+	log.Printf("📋 Dedup Config: backend=%s, ttl=%v, lru size=%d", DedupBackend, DedupTTL, DedupLRUSize)
+
+	// Dead-letter sink, undeliverable events are only logged unless DEADLETTER_SINK is set
+	DeadLetterSink = os.Getenv("DEADLETTER_SINK")
+	switch DeadLetterSink {
+	case "":
+		DeadLetterSink = "none"
+	case "none", "file", "amqp", "http":
+	default:
+		log.Fatalf("❌ Invalid DEADLETTER_SINK value: %s", DeadLetterSink)
+	}
+
+	DeadLetterFile = os.Getenv("DEADLETTER_FILE")
+	if DeadLetterFile == "" {
+		DeadLetterFile = "deadletter.ndjson"
+	}
+
+	DeadLetterAMQPURL = os.Getenv("DEADLETTER_AMQP_URL")
+	if DeadLetterAMQPURL == "" {
+		DeadLetterAMQPURL = RabbitMQURL
+	}
+
+	DeadLetterExchange = os.Getenv("DEADLETTER_EXCHANGE")
+	if DeadLetterExchange == "" {
+		DeadLetterExchange = "deadletter"
+	}
+
+	DeadLetterURL = os.Getenv("DEADLETTER_URL")
+	if DeadLetterSink == "http" && DeadLetterURL == "" {
+		log.Fatal("❌ DEADLETTER_URL environment variable is not set")
+	}
+	log.Printf("📋 Dead-letter Config: sink=%s", DeadLetterSink)
+
+	// Circuit breakers guarding each destination
+	BreakerConsecutiveFailures = 5
+	if consecutiveStr := os.Getenv("BREAKER_CONSECUTIVE_FAILURES"); consecutiveStr != "" {
+		consecutive, err := strconv.Atoi(consecutiveStr)
+		if err != nil || consecutive < 1 {
+			log.Printf("⚠️ Invalid value for BREAKER_CONSECUTIVE_FAILURES: %s. Using default: 5", consecutiveStr)
+		} else {
+			BreakerConsecutiveFailures = consecutive
+		}
+	}
+
+	BreakerErrorRate = 0.5
+	if errorRateStr := os.Getenv("BREAKER_ERROR_RATE"); errorRateStr != "" {
+		errorRate, err := strconv.ParseFloat(errorRateStr, 64)
+		if err != nil || errorRate <= 0 || errorRate > 1 {
+			log.Printf("⚠️ Invalid value for BREAKER_ERROR_RATE: %s. Using default: 0.5", errorRateStr)
+		} else {
+			BreakerErrorRate = errorRate
+		}
+	}
+
+	BreakerMinRequests = 20
+	if minRequestsStr := os.Getenv("BREAKER_MIN_REQUESTS"); minRequestsStr != "" {
+		minRequests, err := strconv.Atoi(minRequestsStr)
+		if err != nil || minRequests < 1 {
+			log.Printf("⚠️ Invalid value for BREAKER_MIN_REQUESTS: %s. Using default: 20", minRequestsStr)
+		} else {
+			BreakerMinRequests = minRequests
+		}
+	}
+
+	BreakerWindow = 10 * time.Second
+	if windowStr := os.Getenv("BREAKER_WINDOW"); windowStr != "" {
+		window, err := strconv.Atoi(windowStr)
+		if err != nil || window <= 0 {
+			log.Printf("⚠️ Invalid value for BREAKER_WINDOW: %s. Using default: 10000ms", windowStr)
+		} else {
+			BreakerWindow = time.Duration(window) * time.Millisecond
+		}
+	}
+
+	BreakerOpenDuration = 5 * time.Second
+	if openDurationStr := os.Getenv("BREAKER_OPEN_DURATION"); openDurationStr != "" {
+		openDuration, err := strconv.Atoi(openDurationStr)
+		if err != nil || openDuration <= 0 {
+			log.Printf("⚠️ Invalid value for BREAKER_OPEN_DURATION: %s. Using default: 5000ms", openDurationStr)
+		} else {
+			BreakerOpenDuration = time.Duration(openDuration) * time.Millisecond
+		}
+	}
+
+	BreakerHalfOpenProbes = 3
+	if probesStr := os.Getenv("BREAKER_HALF_OPEN_PROBES"); probesStr != "" {
+		probes, err := strconv.Atoi(probesStr)
+		if err != nil || probes < 1 {
+			log.Printf("⚠️ Invalid value for BREAKER_HALF_OPEN_PROBES: %s. Using default: 3", probesStr)
+		} else {
+			BreakerHalfOpenProbes = probes
+		}
+	}
+
+	// Optional content-based routing rules, see the rules package
+	RulesFile = os.Getenv("RULES_FILE")
+
+	// Algorithm used by ConsistentHash for events that carry no affinity key
+	FallbackAlgorithm = os.Getenv("ROUTING_FALLBACK_ALGORITHM")
+	if FallbackAlgorithm == "" {
+		FallbackAlgorithm = "AIMD"
+	}
+
+	AffinityExtension = os.Getenv("AFFINITY_EXTENSION")
+	if AffinityExtension == "" {
+		AffinityExtension = "partitionkey"
+	}
+
+	hashRingSizeStr := os.Getenv("HASH_RING_SIZE")
+	if hashRingSizeStr == "" {
+		HashRingSize = 1000
+	} else {
+		hashRingSize, err := strconv.Atoi(hashRingSizeStr)
+		if err != nil || hashRingSize <= 0 {
+			log.Printf("⚠️ Invalid value for HASH_RING_SIZE: %s. Using default: 1000", hashRingSizeStr)
+			HashRingSize = 1000
+		} else {
+			HashRingSize = hashRingSize
+		}
+	}
+
+	peakEWMADecayStr := os.Getenv("PEAK_EWMA_DECAY")
+	if peakEWMADecayStr == "" {
+		PeakEWMADecay = 10 * time.Second
+	} else {
+		peakEWMADecay, err := strconv.Atoi(peakEWMADecayStr)
+		if err != nil || peakEWMADecay <= 0 {
+			log.Printf("⚠️ Invalid value for PEAK_EWMA_DECAY: %s. Using default: 10000ms", peakEWMADecayStr)
+			PeakEWMADecay = 10 * time.Second
+		} else {
+			PeakEWMADecay = time.Duration(peakEWMADecay) * time.Millisecond
+		}
+	}
+
+	qdReqsIntervalStr := os.Getenv("QUEUED_REQUESTS_REFRESH_INTERVAL")
+	if qdReqsIntervalStr == "" {
+		QdReqsRefreshInterval = 1000 * time.Millisecond
+	} else {
+		qdReqsInterval, err := strconv.Atoi(qdReqsIntervalStr)
+		if err != nil || qdReqsInterval <= 0 {
+			log.Printf("⚠️ Invalid value for QUEUED_REQUESTS_REFRESH_INTERVAL: %s. Using default: 1000ms", qdReqsIntervalStr)
+			QdReqsRefreshInterval = 1000 * time.Millisecond
+		} else {
+			QdReqsRefreshInterval = time.Duration(qdReqsInterval) * time.Millisecond
+		}
+	}
+
 	// Load the max and min admission rate from environment variables
 	maxRateStr := os.Getenv("MAX_ADMISSION_RATE")
 	if maxRateStr != "" {
@@ -113,12 +644,151 @@ func LoadConfig() {
 		MinAdmissionRate = 1
 	}
 
-	log.Printf("📋 Admission Rate Config: min=%d, max=%d", MinAdmissionRate, MaxAdmissionRate)
+	// Largest change of a raw admission rate between two updates, up or down. 0 disables
+	// the limit
+	AdmissionRateMaxStep = 0
+	if maxStepStr := os.Getenv("ADMISSION_RATE_MAX_STEP"); maxStepStr != "" {
+		maxStep, err := strconv.ParseFloat(maxStepStr, 64)
+		if err != nil || maxStep < 0 {
+			log.Printf("⚠️ Invalid value for ADMISSION_RATE_MAX_STEP: %s. Using default: 0 (unlimited)", maxStepStr)
+		} else {
+			AdmissionRateMaxStep = maxStep
+		}
+	}
+
+	// Longest AIMD or CUBIC epoch, a multiplicative decrease is forced when the queue has
+	// not emptied for that long. 0 disables the timeout.
+	EpochTimeout = 0
+	if epochTimeoutStr := os.Getenv("EPOCH_TIMEOUT"); epochTimeoutStr != "" {
+		epochTimeout, err := strconv.Atoi(epochTimeoutStr)
+		if err != nil || epochTimeout < 0 {
+			log.Printf("⚠️ Invalid value for EPOCH_TIMEOUT: %s. Using default: 0 (disabled)", epochTimeoutStr)
+		} else {
+			EpochTimeout = time.Duration(epochTimeout) * time.Millisecond
+		}
+	}
+
+	log.Printf("📋 Admission Rate Config: min=%d, max=%d, max step=%.2f, epoch timeout=%v",
+		MinAdmissionRate, MaxAdmissionRate, AdmissionRateMaxStep, EpochTimeout)
+
+	// Normalization of the admission rates into routing weights
+	Normalizer = os.Getenv("NORMALIZER")
+	switch Normalizer {
+	case "":
+		Normalizer = "simple"
+	case "simple", "resource":
+	default:
+		log.Printf("⚠️ Invalid value for NORMALIZER: %s. Using default: simple", Normalizer)
+		Normalizer = "simple"
+	}
+
+	NormalizerMinHeadroom = 0.05
+	if headroomStr := os.Getenv("NORMALIZER_MIN_HEADROOM"); headroomStr != "" {
+		headroom, err := strconv.ParseFloat(headroomStr, 64)
+		if err != nil || headroom < 0 || headroom > 1 {
+			log.Printf("⚠️ Invalid value for NORMALIZER_MIN_HEADROOM: %s. Using default: 0.05", headroomStr)
+		} else {
+			NormalizerMinHeadroom = headroom
+		}
+	}
+	log.Printf("📋 Normalizer Config: normalizer=%s, min headroom=%.2f", Normalizer, NormalizerMinHeadroom)
+
+	// Total throughput shared out as absolute admission rates, measured from the ingress
+	// rate unless TOTAL_THROUGHPUT fixes it in events per second
+	TotalThroughput = 0
+	if throughputStr := os.Getenv("TOTAL_THROUGHPUT"); throughputStr != "" {
+		throughput, err := strconv.ParseFloat(throughputStr, 64)
+		if err != nil || throughput < 0 {
+			log.Printf("⚠️ Invalid value for TOTAL_THROUGHPUT: %s. Using the measured ingress rate", throughputStr)
+		} else {
+			TotalThroughput = throughput
+		}
+	}
+
+	IngressRateDecay = 10 * time.Second
+	if decayStr := os.Getenv("INGRESS_RATE_DECAY"); decayStr != "" {
+		decay, err := strconv.Atoi(decayStr)
+		if err != nil || decay <= 0 {
+			log.Printf("⚠️ Invalid value for INGRESS_RATE_DECAY: %s. Using default: 10000ms", decayStr)
+		} else {
+			IngressRateDecay = time.Duration(decay) * time.Millisecond
+		}
+	}
+	log.Printf("📋 Throughput Config: total=%.2f events/s (0 = measured), ingress decay=%v", TotalThroughput, IngressRateDecay)
+
+	// queued_requests of a consumer above which its service is congested, 0 disables the signal
+	CongestionQueuedRequests = 0
+	if queuedStr := os.Getenv("CONGESTION_QUEUED_REQUESTS"); queuedStr != "" {
+		queued, err := strconv.Atoi(queuedStr)
+		if err != nil || queued < 0 {
+			log.Printf("⚠️ Invalid value for CONGESTION_QUEUED_REQUESTS: %s. Using default: 0 (disabled)", queuedStr)
+		} else {
+			CongestionQueuedRequests = queued
+		}
+	}
+
+	// Shortest time between two congestion epochs of the same service
+	CongestionCooldown = 5 * time.Second
+	if cooldownStr := os.Getenv("CONGESTION_COOLDOWN"); cooldownStr != "" {
+		cooldown, err := strconv.Atoi(cooldownStr)
+		if err != nil || cooldown < 0 {
+			log.Printf("⚠️ Invalid value for CONGESTION_COOLDOWN: %s. Using default: 5000ms", cooldownStr)
+		} else {
+			CongestionCooldown = time.Duration(cooldown) * time.Millisecond
+		}
+	}
+	log.Printf("📋 Congestion Config: queued requests threshold=%d (0 = disabled), cooldown=%v",
+		CongestionQueuedRequests, CongestionCooldown)
 
 	// Load the parameters for each service from environment variables
+	seenNames := make(map[string]bool)
 	for i := 0; i < NumServices; i++ {
 		serviceIndex := i // 0-based index for array access, 1-based for logs
 
+		// Load the service name, used for Redis keys, Pub/Sub channels and metric labels
+		name := os.Getenv(fmt.Sprintf("SERVICE%d_NAME", serviceIndex+1))
+		if name == "" {
+			name = fmt.Sprintf("service%d", serviceIndex+1)
+		}
+		if seenNames[name] {
+			log.Fatalf("❌ Duplicate service name %q in SERVICE%d_NAME", name, serviceIndex+1)
+		}
+		seenNames[name] = true
+		ServiceNames[serviceIndex] = name
+
+		// Load the target URL, defaulting to the in-cluster address of the service
+		serviceURL := os.Getenv(fmt.Sprintf("SERVICE%d_URL", serviceIndex+1))
+		if serviceURL == "" {
+			serviceURL = fmt.Sprintf("http://%s.rabbitmq-setup.svc.cluster.local", name)
+		} else if parsed, err := url.Parse(serviceURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			log.Fatalf("❌ Invalid SERVICE%d_URL value: %s", serviceIndex+1, serviceURL)
+		}
+		ServiceURLs[serviceIndex] = serviceURL
+		ServicePaths[serviceIndex] = os.Getenv(fmt.Sprintf("SERVICE%d_PATH", serviceIndex+1))
+
+		// Load extra headers as a comma separated list of Key=Value pairs
+		headers := make(map[string]string)
+		headersStr := os.Getenv(fmt.Sprintf("SERVICE%d_HEADERS", serviceIndex+1))
+		for _, pair := range strings.Split(headersStr, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				log.Fatalf("❌ Invalid SERVICE%d_HEADERS entry: %s", serviceIndex+1, pair)
+			}
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		ServiceHeaders[serviceIndex] = headers
+
+		// Load the name of the consumer Kubernetes service, used to scrape its pods
+		consumer := os.Getenv(fmt.Sprintf("SERVICE%d_CONSUMER", serviceIndex+1))
+		if consumer == "" {
+			consumer = strings.Replace(name, "service", "consumer-service-", 1)
+		}
+		ServiceConsumers[serviceIndex] = consumer
+		log.Printf("📋 Service %d: name=%s, url=%s%s, consumer=%s", serviceIndex+1, name, serviceURL, ServicePaths[serviceIndex], consumer)
+
 		// Load Initial CurrWeight
 		currWeightStr := os.Getenv(fmt.Sprintf("SERVICE%d_INITIAL_CURR_WEIGHT", serviceIndex+1)) // Use serviceIndex + 1 for environment variable name
 		currWeight, err := strconv.ParseFloat(currWeightStr, 64)
@@ -168,8 +838,101 @@ func LoadConfig() {
 		} else {
 			Betas[serviceIndex] = beta
 		}
+
+		// Load the admission rate bounds, defaulting to MIN_ADMISSION_RATE and MAX_ADMISSION_RATE
+		MinAdmissionRates[serviceIndex] = float64(MinAdmissionRate)
+		if minRateStr := os.Getenv(fmt.Sprintf("SERVICE%d_MIN_ADMISSION_RATE", serviceIndex+1)); minRateStr != "" {
+			minRate, err := strconv.ParseFloat(minRateStr, 64)
+			if err != nil || minRate < 0 {
+				log.Printf("⚠️ Invalid value for SERVICE%d_MIN_ADMISSION_RATE. Using default: %d", serviceIndex+1, MinAdmissionRate)
+			} else {
+				MinAdmissionRates[serviceIndex] = minRate
+			}
+		}
+
+		MaxAdmissionRates[serviceIndex] = float64(MaxAdmissionRate)
+		if maxRateStr := os.Getenv(fmt.Sprintf("SERVICE%d_MAX_ADMISSION_RATE", serviceIndex+1)); maxRateStr != "" {
+			maxRate, err := strconv.ParseFloat(maxRateStr, 64)
+			if err != nil || maxRate <= 0 {
+				log.Printf("⚠️ Invalid value for SERVICE%d_MAX_ADMISSION_RATE. Using default: %d", serviceIndex+1, MaxAdmissionRate)
+			} else {
+				MaxAdmissionRates[serviceIndex] = maxRate
+			}
+		}
+		if MinAdmissionRates[serviceIndex] > MaxAdmissionRates[serviceIndex] {
+			log.Fatalf("❌ Admission rate bounds of service %d are inverted: min=%.2f > max=%.2f",
+				serviceIndex+1, MinAdmissionRates[serviceIndex], MaxAdmissionRates[serviceIndex])
+		}
+
+		// Load the control law computing the admission rate of the service
+		ControlLaws[serviceIndex] = os.Getenv(fmt.Sprintf("SERVICE%d_CONTROL_LAW", serviceIndex+1))
+		switch ControlLaws[serviceIndex] {
+		case "":
+			ControlLaws[serviceIndex] = "aimd"
+		case "aimd", "cubic", "pid", "vegas":
+		default:
+			log.Printf("⚠️ Invalid value for SERVICE%d_CONTROL_LAW: %s. Using default: aimd", serviceIndex+1, ControlLaws[serviceIndex])
+			ControlLaws[serviceIndex] = "aimd"
+		}
+
+		CubicCs[serviceIndex] = serviceFloat(serviceIndex, "CUBIC_C", 0.4, false)
+		PIDSetpoints[serviceIndex] = serviceFloat(serviceIndex, "PID_SETPOINT", 10, true)
+		PIDKps[serviceIndex] = serviceFloat(serviceIndex, "PID_KP", 0.5, true)
+		PIDKis[serviceIndex] = serviceFloat(serviceIndex, "PID_KI", 0.1, true)
+		PIDKds[serviceIndex] = serviceFloat(serviceIndex, "PID_KD", 0, true)
+		VegasAlphas[serviceIndex] = serviceFloat(serviceIndex, "VEGAS_ALPHA", 2, true)
+		VegasBetas[serviceIndex] = serviceFloat(serviceIndex, "VEGAS_BETA", 4, true)
+		VegasSteps[serviceIndex] = serviceFloat(serviceIndex, "VEGAS_STEP", 1, false)
+		if VegasAlphas[serviceIndex] > VegasBetas[serviceIndex] {
+			log.Fatalf("❌ Vegas thresholds of service %d are inverted: alpha=%.2f > beta=%.2f",
+				serviceIndex+1, VegasAlphas[serviceIndex], VegasBetas[serviceIndex])
+		}
+		log.Printf("📋 Service %s Control Law: %s", ServiceNames[serviceIndex], ControlLaws[serviceIndex])
 	}
 
 	log.Println("✅ All service-specific parameters loaded")
 
 }
+
+// Helper function to load the float parameter SERVICE<n>_<name> of a service. Negative
+// values, and zero unless allowZero, fall back to the default.
+func serviceFloat(serviceIndex int, name string, defaultValue float64, allowZero bool) float64 {
+	key := fmt.Sprintf("SERVICE%d_%s", serviceIndex+1, name)
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil || value < 0 || (value == 0 && !allowZero) {
+		log.Printf("⚠️ Invalid value for %s: %s. Using default: %g", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to parse DISPATCH_PRIORITY_WEIGHTS, a comma separated list of
+// priority=weight pairs. Every weight must be at least 1 so that no priority starves.
+func parsePriorityWeights(value string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		priority, weightStr, ok := strings.Cut(pair, "=")
+		priority = strings.ToLower(strings.TrimSpace(priority))
+		if !ok {
+			return nil, fmt.Errorf("missing weight in %q", pair)
+		}
+		switch priority {
+		case "high", "normal", "low":
+		default:
+			return nil, fmt.Errorf("unknown priority %q", priority)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil || weight < 1 {
+			return nil, fmt.Errorf("weight of %s must be a positive integer", priority)
+		}
+		weights[priority] = weight
+	}
+	return weights, nil
+}
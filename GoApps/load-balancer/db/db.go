@@ -2,8 +2,8 @@ package db
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,9 +18,15 @@ var (
 
 type Service struct {
 	Name             string
-	RawAdmissionRate float64 // Raw value used for AIMD and admission controllers
-	CurrWeight       float64 // Normalized value used for routing
-	EmptyQWeight     float64 // Baseline value for raw admission rate when queue is empty
+	URL              string            // Base URL events are sent to
+	Path             string            // Optional path appended to URL
+	Headers          map[string]string // Extra HTTP headers sent with every event
+	Consumer         string            // Kubernetes service of the consumer, used for metrics
+	RawAdmissionRate float64           // Raw value used for AIMD and admission controllers
+	CurrWeight       float64           // Normalized value used for routing
+	EmptyQWeight     float64           // Baseline value for raw admission rate when queue is empty
+	MinAdmissionRate float64           // Floor of RawAdmissionRate
+	MaxAdmissionRate float64           // Ceiling of RawAdmissionRate
 	Beta             float64
 	Alpha            int
 }
@@ -42,6 +48,14 @@ func NewRedisClient() *redis.Client {
 	})
 }
 
+// TargetURL returns the URL events for the service are sent to
+func (s *Service) TargetURL() string {
+	if s.Path == "" {
+		return s.URL
+	}
+	return strings.TrimSuffix(s.URL, "/") + "/" + strings.TrimPrefix(s.Path, "/")
+}
+
 func SaveServiceToRedis(rdb *redis.Client, service *Service) error {
 	key := ServiceKeyPrefix + service.Name
 	err := rdb.HSet(Ctx, key, map[string]interface{}{
@@ -65,14 +79,19 @@ func KeyExists(rdb *redis.Client, key string) (bool, error) {
 func InitializeServices(rdb *redis.Client) {
 	ServicesMap = make(map[string]*Service)
 	for i := 0; i < config.NumServices; i++ {
-		name := fmt.Sprintf("service%d", i+1)
 		service := &Service{
-			Name:             name,
+			Name:             config.ServiceNames[i],
+			URL:              config.ServiceURLs[i],
+			Path:             config.ServicePaths[i],
+			Headers:          config.ServiceHeaders[i],
+			Consumer:         config.ServiceConsumers[i],
 			CurrWeight:       config.InitialCurrWeights[i],   // Use the loaded value from config
 			EmptyQWeight:     config.InitialEmptyQWeights[i], // Use the loaded value from config
 			RawAdmissionRate: config.RawAdmissionRates[i],    // Use the loaded value from config
 			Beta:             config.Betas[i],                // Use the loaded value from config
 			Alpha:            config.Alphas[i],               // Use the loaded value from config
+			MinAdmissionRate: config.MinAdmissionRates[i],
+			MaxAdmissionRate: config.MaxAdmissionRates[i],
 		}
 
 		ServicesMap[service.Name] = service
@@ -81,6 +100,11 @@ func InitializeServices(rdb *redis.Client) {
 		if err := SaveServiceToRedis(rdb, service); err != nil {
 			log.Fatalf("❌ Error saving service %s to Redis: %v", service.Name, err)
 		}
+
+		// Drop the tk of a previous run, the service follows the global tk until its first epoch
+		if err := rdb.HDel(Ctx, ServiceKeyPrefix+service.Name, TkKey).Err(); err != nil {
+			log.Fatalf("❌ Error resetting tk of service %s in Redis: %v", service.Name, err)
+		}
 	}
 	LastUpdateTime = time.Now()
 	log.Println("✅ Services initialized")
@@ -0,0 +1,109 @@
+package deadletter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"load-balancer/config"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// Record is an undeliverable event together with the metadata of its last delivery attempt
+type Record struct {
+	Event       cloudevents.Event `json:"event"`
+	Stage       string            `json:"stage"`                 // Component that gave up on the event
+	Destination string            `json:"destination,omitempty"` // Last service the event was sent to
+	Attempts    int               `json:"attempts"`
+	LastError   string            `json:"last_error"`
+	ReceivedAt  time.Time         `json:"received_at"`
+	FailedAt    time.Time         `json:"failed_at"`
+}
+
+// Sink stores dead-lettered records. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(record Record) error
+	Close() error
+}
+
+var (
+	sink   Sink = nopSink{}
+	sinkMu sync.RWMutex
+)
+
+// Init opens the sink selected by DEADLETTER_SINK. Until it is called records are only logged.
+func Init() error {
+	var s Sink
+	var err error
+	switch config.DeadLetterSink {
+	case "file":
+		s, err = NewFileSink(config.DeadLetterFile)
+	case "amqp":
+		s, err = NewAMQPSink(config.DeadLetterAMQPURL, config.DeadLetterExchange)
+	case "http":
+		s = NewHTTPSink(config.DeadLetterURL)
+	default:
+		s = nopSink{}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s dead-letter sink: %w", config.DeadLetterSink, err)
+	}
+
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sink = s
+	log.Printf("🪦 Dead-letter sink: %s", config.DeadLetterSink)
+	return nil
+}
+
+// Write stores a record in the configured sink. A failing sink is only logged since
+// there is nowhere left to put the event.
+func Write(record Record) {
+	if record.FailedAt.IsZero() {
+		record.FailedAt = time.Now()
+	}
+
+	sinkMu.RLock()
+	s := sink
+	sinkMu.RUnlock()
+
+	log.Printf("🪦 Dead-lettering event %s from %s after %d attempt(s): %s",
+		record.Event.ID(), record.Stage, record.Attempts, record.LastError)
+	if err := s.Write(record); err != nil {
+		log.Printf("❌ Failed to write event %s to the dead-letter sink: %v", record.Event.ID(), err)
+	}
+}
+
+// ReadRecords calls fn for every record of an NDJSON stream written by the file sink,
+// stopping at the first error.
+func ReadRecords(r io.Reader, fn func(Record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Events may carry large payloads
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// nopSink drops records, leaving only the log line written by Write
+type nopSink struct{}
+
+func (nopSink) Write(Record) error { return nil }
+
+func (nopSink) Close() error { return nil }
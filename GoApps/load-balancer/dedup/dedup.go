@@ -0,0 +1,114 @@
+package dedup
+
+import (
+	"log"
+	"sync"
+
+	"load-balancer/config"
+	"load-balancer/db"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// State of an event key in the store
+type State int
+
+const (
+	// StateNew means the key was not recorded, the caller now holds the claim on it
+	StateNew State = iota
+	// StateInFlight means another attempt claimed the key and has not finished yet
+	StateInFlight
+	// StateDelivered means the event was already delivered
+	StateDelivered
+)
+
+// Store remembers event keys for DEDUP_TTL. Implementations must be safe for concurrent use.
+type Store interface {
+	// Claim marks the key as in flight unless it is already recorded, and returns the
+	// state it was in before
+	Claim(key string) (State, error)
+	// Deliver records the key as delivered
+	Deliver(key string) error
+	// Forget removes the key so the next event with it is processed again
+	Forget(key string) error
+}
+
+var (
+	store   Store
+	storeMu sync.RWMutex
+)
+
+// Init creates the store selected by DEDUP_BACKEND. With the default "none" every event
+// is processed.
+func Init() {
+	var s Store
+	switch config.DedupBackend {
+	case "redis":
+		s = NewRedisStore(db.NewRedisClient(), config.DedupTTL)
+	case "memory":
+		s = NewLRUStore(config.DedupLRUSize, config.DedupTTL)
+	default:
+		log.Println("🔁 De-duplication disabled")
+		return
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+	log.Printf("🔁 De-duplicating events with the %s backend for %v", config.DedupBackend, config.DedupTTL)
+}
+
+// Helper function to build the key of an event, ids are only unique per source
+func keyOf(event cloudevents.Event) string {
+	return event.Source() + "|" + event.ID()
+}
+
+// Helper function to get the store selected by Init, nil when de-duplication is disabled
+func currentStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+// Claim marks the event as in flight and returns the state it was in before. Only a
+// StateNew result hands the event to the caller, who must then either call Delivered or
+// Forget. When the store is unavailable the event is treated as new, a duplicate is
+// better than a lost event.
+func Claim(event cloudevents.Event) State {
+	s := currentStore()
+	if s == nil {
+		return StateNew
+	}
+
+	state, err := s.Claim(keyOf(event))
+	if err != nil {
+		log.Printf("⚠️ De-duplication check failed for event %s: %v", event.ID(), err)
+		return StateNew
+	}
+	return state
+}
+
+// Delivered records the claimed event as delivered, so that later redeliveries are dropped
+func Delivered(event cloudevents.Event) {
+	s := currentStore()
+	if s == nil {
+		return
+	}
+
+	if err := s.Deliver(keyOf(event)); err != nil {
+		log.Printf("⚠️ Failed to record delivery of event %s: %v", event.ID(), err)
+	}
+}
+
+// Forget removes the event from the store, used for events handed back to the broker or
+// dead-lettered so that their redelivery or replay is not dropped
+func Forget(event cloudevents.Event) {
+	s := currentStore()
+	if s == nil {
+		return
+	}
+
+	if err := s.Forget(keyOf(event)); err != nil {
+		log.Printf("⚠️ Failed to forget event %s: %v", event.ID(), err)
+	}
+}
@@ -0,0 +1,86 @@
+package dedup
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRUStore keeps seen events in memory, for a single replica or tests. Once it holds
+// size keys the least recently seen one is evicted, even before its TTL elapsed.
+type LRUStore struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // Front is the most recently seen key
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	delivered bool
+	expires   time.Time
+}
+
+func NewLRUStore(size int, ttl time.Duration) *LRUStore {
+	return &LRUStore{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+func (s *LRUStore) Claim(key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if element, ok := s.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		s.order.MoveToFront(element)
+		if now.Before(entry.expires) {
+			if entry.delivered {
+				return StateDelivered, nil
+			}
+			return StateInFlight, nil
+		}
+		// Expired, claim it again as a new event
+		entry.delivered = false
+		entry.expires = now.Add(s.ttl)
+		return StateNew, nil
+	}
+
+	s.entries[key] = s.order.PushFront(&lruEntry{key: key, expires: now.Add(s.ttl)})
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruEntry).key)
+	}
+	return StateNew, nil
+}
+
+func (s *LRUStore) Deliver(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A claim evicted in the meantime is not recorded again
+	if element, ok := s.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.delivered = true
+		entry.expires = time.Now().Add(s.ttl)
+		s.order.MoveToFront(element)
+	}
+	return nil
+}
+
+func (s *LRUStore) Forget(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+		delete(s.entries, key)
+	}
+	return nil
+}
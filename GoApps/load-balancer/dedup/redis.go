@@ -0,0 +1,63 @@
+package dedup
+
+import (
+	"fmt"
+	"time"
+
+	"load-balancer/db"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Prefix of the Redis keys holding seen events
+const redisKeyPrefix = "dedup:"
+
+// Values of the Redis keys
+const (
+	redisInFlight  = "in-flight"
+	redisDelivered = "delivered"
+)
+
+// RedisStore shares seen events between all load balancer replicas. Every key expires
+// on its own after the TTL, so a claim left behind by a crashed replica is released too.
+type RedisStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewRedisStore(rdb *redis.Client, ttl time.Duration) *RedisStore {
+	return &RedisStore{rdb: rdb, ttl: ttl}
+}
+
+func (s *RedisStore) Claim(key string) (State, error) {
+	added, err := s.rdb.SetNX(db.Ctx, redisKeyPrefix+key, redisInFlight, s.ttl).Result()
+	if err != nil {
+		return StateNew, err
+	}
+	if added {
+		return StateNew, nil
+	}
+
+	value, err := s.rdb.Get(db.Ctx, redisKeyPrefix+key).Result()
+	switch {
+	case err == redis.Nil:
+		// Forgotten in between, let the broker redeliver it rather than race for it
+		return StateInFlight, nil
+	case err != nil:
+		return StateNew, err
+	case value == redisInFlight:
+		return StateInFlight, nil
+	case value == redisDelivered:
+		return StateDelivered, nil
+	default:
+		return StateNew, fmt.Errorf("unexpected value %q of key %s", value, redisKeyPrefix+key)
+	}
+}
+
+func (s *RedisStore) Deliver(key string) error {
+	return s.rdb.Set(db.Ctx, redisKeyPrefix+key, redisDelivered, s.ttl).Err()
+}
+
+func (s *RedisStore) Forget(key string) error {
+	return s.rdb.Del(db.Ctx, redisKeyPrefix+key).Err()
+}
@@ -0,0 +1,239 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"load-balancer/config"
+	"load-balancer/rabbitmq"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"github.com/streadway/amqp"
+)
+
+// Header prefixes of CloudEvents attributes in binary mode AMQP messages
+var attributePrefixes = []string{"cloudevents:", "cloudevents_", "ce-", "ce_"}
+
+// Retry queues declared so far, by delay
+var retryQueues sync.Map
+
+// Header counting how often the load balancer handed a message back to its queue
+const redeliveriesHeader = "x-lb-redeliveries"
+
+// StartAMQPReceiver handles messages consumed from the trigger queue, one worker per
+// prefetched message. A message is acked once Receive ACKs its event, so only after it
+// was delivered or dropped. When Receive NACKs it the message is parked in a retry queue
+// for a delay that doubles with every redelivery, from which the broker dead-letters it
+// back to the tail of queueName, and after AMQP_MAX_REDELIVERIES its event is
+// dead-lettered to the sink instead. Messages that
+// are not valid CloudEvents are rejected without requeue. It returns when the delivery
+// channel closes.
+func StartAMQPReceiver(deliveries <-chan amqp.Delivery, workers int, ch *amqp.Channel, queueName string) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range deliveries {
+				handleDelivery(delivery, ch, queueName)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func handleDelivery(delivery amqp.Delivery, ch *amqp.Channel, queueName string) {
+	received := time.Now()
+	event, err := eventFromDelivery(delivery)
+	if err != nil {
+		log.Printf("❌ Rejecting malformed message %s: %v", delivery.MessageId, err)
+		if err := delivery.Nack(false, false); err != nil {
+			log.Printf("❌ Failed to reject message %s: %v", delivery.MessageId, err)
+		}
+		return
+	}
+
+	result := Receive(context.Background(), event)
+	if !cloudevents.IsACK(result) {
+		redeliveries := redeliveriesOf(delivery)
+		if config.AMQPMaxRedeliveries > 0 && redeliveries >= config.AMQPMaxRedeliveries {
+			log.Printf("🪦 Giving up on event %s after %d redeliveries: %v", event.ID(), redeliveries, result)
+			deadLetter(event, fmt.Errorf("gave up after %d redeliveries: %w", redeliveries, result), received)
+		} else if !requeue(delivery, ch, queueName, redeliveries+1) {
+			return
+		}
+	}
+	if err := delivery.Ack(false); err != nil {
+		log.Printf("❌ Failed to ack event %s: %v", event.ID(), err)
+	}
+}
+
+// Helper function to read how often a message was handed back to the queue, counting a
+// redelivery by the broker itself, e.g. after a lost connection, as one more
+func redeliveriesOf(delivery amqp.Delivery) int {
+	count := 0
+	switch value := delivery.Headers[redeliveriesHeader].(type) {
+	case int8:
+		count = int(value)
+	case int16:
+		count = int(value)
+	case int32:
+		count = int(value)
+	case int64:
+		count = int(value)
+	}
+	if delivery.Redelivered {
+		count++
+	}
+	return count
+}
+
+// Helper function to get the delay before the given redelivery, AMQP_REQUEUE_DELAY
+// doubled for every earlier one and capped at AMQP_REQUEUE_MAX_DELAY
+func requeueDelay(redelivery int) time.Duration {
+	delay := config.AMQPRequeueDelay
+	for i := 1; i < redelivery && delay < config.AMQPRequeueMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, config.AMQPRequeueMaxDelay)
+}
+
+// Helper function to hand a NACKed message back to its queue after the requeue delay,
+// carrying the number of the redelivery in its headers. The message waits in a retry
+// queue whose TTL is the delay, so the worker moves on to the next message meanwhile.
+// The caller acks the original message when it returns true. When parking the message
+// fails the original is nacked with requeue right away and false is returned.
+func requeue(delivery amqp.Delivery, ch *amqp.Channel, queueName string, redelivery int) bool {
+	delay := requeueDelay(redelivery)
+	retryQueue, err := retryQueueFor(ch, queueName, delay)
+	if err != nil {
+		log.Printf("❌ Failed to requeue message %s, nacking it: %v", delivery.MessageId, err)
+		if err := delivery.Nack(false, true); err != nil {
+			log.Printf("❌ Failed to nack message %s: %v", delivery.MessageId, err)
+		}
+		return false
+	}
+	log.Printf("↩️ Requeueing message %s in %v through %s (redelivery %d)", delivery.MessageId, delay, retryQueue, redelivery)
+
+	headers := make(amqp.Table, len(delivery.Headers)+1)
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	headers[redeliveriesHeader] = int32(redelivery)
+
+	err = ch.Publish("", retryQueue, false, false, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     delivery.ContentType,
+		ContentEncoding: delivery.ContentEncoding,
+		DeliveryMode:    delivery.DeliveryMode,
+		Priority:        delivery.Priority,
+		CorrelationId:   delivery.CorrelationId,
+		ReplyTo:         delivery.ReplyTo,
+		Expiration:      delivery.Expiration,
+		MessageId:       delivery.MessageId,
+		Timestamp:       delivery.Timestamp,
+		Type:            delivery.Type,
+		UserId:          delivery.UserId,
+		AppId:           delivery.AppId,
+		Body:            delivery.Body,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to requeue message %s, nacking it: %v", delivery.MessageId, err)
+		if err := delivery.Nack(false, true); err != nil {
+			log.Printf("❌ Failed to nack message %s: %v", delivery.MessageId, err)
+		}
+		return false
+	}
+	return true
+}
+
+// Helper function to get the retry queue of the given delay, declaring it on first use.
+// Delays double per redelivery, so there is only a handful of them.
+func retryQueueFor(ch *amqp.Channel, queueName string, delay time.Duration) (string, error) {
+	if retryQueue, ok := retryQueues.Load(delay); ok {
+		return retryQueue.(string), nil
+	}
+
+	retryQueue, err := rabbitmq.DeclareRetryQueue(ch, queueName, delay)
+	if err != nil {
+		return "", err
+	}
+	retryQueues.Store(delay, retryQueue)
+	return retryQueue, nil
+}
+
+// Helper function to decode a message in structured mode (a JSON CloudEvent body) or
+// binary mode (attributes in headers, the body as data). In binary mode the core
+// attributes may come with or without prefix, extensions need one of attributePrefixes.
+func eventFromDelivery(delivery amqp.Delivery) (cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+	if strings.HasPrefix(delivery.ContentType, cloudevents.ApplicationCloudEventsJSON) {
+		if err := json.Unmarshal(delivery.Body, &event); err != nil {
+			return event, err
+		}
+		return event, event.Validate()
+	}
+
+	for header, value := range delivery.Headers {
+		name := strings.ToLower(header)
+		prefixed := false
+		for _, prefix := range attributePrefixes {
+			if strings.HasPrefix(name, prefix) {
+				name = strings.TrimPrefix(name, prefix)
+				prefixed = true
+				break
+			}
+		}
+
+		str, err := types.ToString(value)
+		if err != nil {
+			str = fmt.Sprint(value)
+		}
+		switch name {
+		case "specversion":
+			event.SetSpecVersion(str)
+		case "id":
+			event.SetID(str)
+		case "source":
+			event.SetSource(str)
+		case "type":
+			event.SetType(str)
+		case "subject":
+			event.SetSubject(str)
+		case "dataschema":
+			event.SetDataSchema(str)
+		case "datacontenttype":
+			event.SetDataContentType(str)
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, str)
+			if err != nil {
+				return event, fmt.Errorf("invalid time attribute %q: %w", str, err)
+			}
+			event.SetTime(t)
+		default:
+			if !prefixed {
+				continue
+			}
+			if err := event.Context.SetExtension(name, value); err != nil {
+				log.Printf("⚠️ Ignoring extension %s of message %s: %v", name, delivery.MessageId, err)
+			}
+		}
+	}
+
+	contentType := event.DataContentType()
+	if contentType == "" {
+		contentType = delivery.ContentType
+	}
+	if len(delivery.Body) > 0 {
+		if err := event.SetData(contentType, delivery.Body); err != nil {
+			return event, err
+		}
+	}
+	return event, event.Validate()
+}
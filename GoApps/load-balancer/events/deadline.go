@@ -0,0 +1,27 @@
+package events
+
+import (
+	"log"
+	"time"
+
+	"load-balancer/deadline"
+	"load-balancer/metrics"
+	"load-balancer/routing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// Helper function to discard an event if its deadline passed. Expired events are
+// dead-lettered and counted under the given stage, and the caller ACKs them since a
+// redelivery would be just as stale.
+func expired(event cloudevents.Event, stage string, received time.Time) bool {
+	expiry, ok := deadline.Of(event)
+	if !ok || time.Now().Before(expiry) {
+		return false
+	}
+
+	log.Printf("⌛ Event %s expired at %s, discarding it at %s", event.ID(), expiry.Format(time.RFC3339Nano), stage)
+	metrics.IncEventsExpired(stage)
+	deadLetter(event, routing.ErrExpired, received)
+	return true
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"load-balancer/config"
+	"load-balancer/dedup"
+	"load-balancer/metrics"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+var (
+	// Bounded queues between the receiver and the dispatch workers, one per priority,
+	// nil when routing synchronously
+	dispatchQueues map[string]chan dispatchJob
+)
+
+type dispatchJob struct {
+	event    cloudevents.Event
+	priority string
+	enqueued time.Time
+}
+
+// StartDispatcher starts DISPATCH_WORKERS workers that route queued events, so that
+// the receiver no longer waits for the consumer. Every priority has its own queue and
+// workers favor higher priorities by DISPATCH_PRIORITY_WEIGHTS, so bulk traffic cannot
+// delay urgent events. With zero workers events keep being routed inside the receiver
+// callback and priorities have no effect on the order.
+func StartDispatcher() {
+	if config.DispatchWorkers == 0 {
+		log.Println("📬 Dispatch worker pool disabled, routing events synchronously")
+		return
+	}
+
+	dispatchQueues = make(map[string]chan dispatchJob, len(priorities))
+	for _, priority := range priorities {
+		dispatchQueues[priority] = make(chan dispatchJob, config.DispatchQueueSize)
+	}
+	for i := 0; i < config.DispatchWorkers; i++ {
+		go dispatchWorker(i)
+	}
+	log.Printf("📬 Started %d dispatch workers with queue size %d per priority", config.DispatchWorkers, config.DispatchQueueSize)
+}
+
+// Helper function to queue an event for the workers. A full queue either rejects the
+// event with 429 so the broker retries it later, or blocks the receiver until a slot
+// frees up or the broker gives up on the request.
+func enqueue(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+	job := dispatchJob{event: event, priority: priorityOf(event), enqueued: time.Now()}
+	queue := dispatchQueues[job.priority]
+
+	if config.DispatchOverflow == "block" {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			log.Printf("⚠️ Gave up queueing %s priority event %s: %v", job.priority, event.ID(), ctx.Err())
+			metrics.IncDispatchRejected(job.priority)
+			metrics.IncEventsDropped(job.priority, "rejected")
+			return cloudevents.NewHTTPResult(http.StatusServiceUnavailable, "dispatch queue full")
+		}
+	} else {
+		select {
+		case queue <- job:
+		default:
+			log.Printf("⚠️ Dispatch queue for %s priority full, rejecting event %s", job.priority, event.ID())
+			metrics.IncDispatchRejected(job.priority)
+			metrics.IncEventsDropped(job.priority, "rejected")
+			return cloudevents.NewHTTPResult(http.StatusTooManyRequests, "dispatch queue full")
+		}
+	}
+
+	metrics.UpdateDispatchQueueDepth(job.priority, len(queue))
+	return cloudevents.ResultACK
+}
+
+// Helper function to take the next job. While several priorities have queued events the
+// workers serve them in proportion to DISPATCH_PRIORITY_WEIGHTS, so high priority events
+// go first most of the time but low priority ones still drain under sustained load.
+func nextJob() dispatchJob {
+	for {
+		var ready []string
+		for _, priority := range priorities {
+			if len(dispatchQueues[priority]) > 0 {
+				ready = append(ready, priority)
+			}
+		}
+
+		if len(ready) == 0 {
+			// All queues were empty, take whichever event arrives first
+			select {
+			case job := <-dispatchQueues[PriorityHigh]:
+				return job
+			case job := <-dispatchQueues[PriorityNormal]:
+				return job
+			case job := <-dispatchQueues[PriorityLow]:
+				return job
+			}
+		}
+
+		// Another worker may have taken the last event of the chosen queue meanwhile
+		select {
+		case job := <-dispatchQueues[priorityShares.next(ready)]:
+			return job
+		default:
+		}
+	}
+}
+
+// Smooth weighted round robin over the priorities, shared by all workers
+type shares struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+var priorityShares = &shares{current: make(map[string]int)}
+
+// Helper function to choose the priority to serve among those with queued events. Every
+// candidate gains its weight and the chosen one pays back the total, the same scheme the
+// smooth weighted selector uses for services.
+func (s *shares) next(ready []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A priority whose queue ran empty starts over once it has events again
+	for priority := range s.current {
+		if !slices.Contains(ready, priority) {
+			delete(s.current, priority)
+		}
+	}
+
+	total := 0
+	chosen := ""
+	for _, priority := range ready {
+		weight := config.PriorityWeights[priority]
+		s.current[priority] += weight
+		total += weight
+		if chosen == "" || s.current[priority] > s.current[chosen] {
+			chosen = priority
+		}
+	}
+	s.current[chosen] -= total
+	return chosen
+}
+
+func dispatchWorker(workerID int) {
+	for {
+		job := nextJob()
+		metrics.UpdateDispatchQueueDepth(job.priority, len(dispatchQueues[job.priority]))
+		metrics.ObserveDispatchWait(job.priority, time.Since(job.enqueued))
+		if expired(job.event, "dequeue", job.enqueued) {
+			continue
+		}
+
+		// The broker already got its ACK, so a failed event can only be dead-lettered here
+		if result := route(job.event); !cloudevents.IsACK(result) {
+			log.Printf("❌ Worker %d: failed to dispatch event %s: %v", workerID, job.event.ID(), result)
+			deadLetter(job.event, result, job.enqueued)
+		} else {
+			dedup.Delivered(job.event)
+			metrics.ObserveEventLatency(job.priority, time.Since(job.enqueued))
+		}
+	}
+}
@@ -2,12 +2,20 @@ package events
 
 import (
 	"context"
+	"errors"
 	"log"
 	"math/rand"
+	"net/http"
 	"time"
 
+	"load-balancer/config"
 	"load-balancer/db"
+	"load-balancer/deadletter"
+	"load-balancer/dedup"
+	"load-balancer/metrics"
 	"load-balancer/routing"
+	"load-balancer/rules"
+	"load-balancer/weights"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 )
@@ -22,14 +30,122 @@ func StartReceiver() {
 		log.Fatalf("❌ Failed to create client: %v", err)
 	}
 
-	err = c.StartReceiver(context.Background(), func(ctx context.Context, event cloudevents.Event) {
-		Receive(event)
+	err = c.StartReceiver(context.Background(), func(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+		return Receive(ctx, event)
 	})
 	if err != nil {
 		log.Fatalf("❌ Failed to start receiver: %v", err)
 	}
 }
 
-func Receive(event cloudevents.Event) {
-	routing.SelectedAlgorithm.RouteEvent(event, db.ServicesMap)
+// Receive routes the event and returns the response for the broker: an ACK when the
+// event was delivered or its failure is configured to be dropped, a NACK otherwise.
+// When the dispatch worker pool is running the event is only queued, and the ACK
+// means it was accepted for dispatch. Events whose deadline already passed and
+// redeliveries of events that were already delivered are ACKed without being routed,
+// redeliveries of events still being delivered are NACKed so that the broker keeps
+// them until the first attempt finished.
+func Receive(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+	received := time.Now()
+	if expired(event, "receive", received) {
+		return cloudevents.ResultACK
+	}
+	switch dedup.Claim(event) {
+	case dedup.StateDelivered:
+		log.Printf("🔁 Dropping duplicate event %s from %s", event.ID(), event.Source())
+		metrics.IncDuplicateEvents()
+		return cloudevents.ResultACK
+	case dedup.StateInFlight:
+		log.Printf("🔁 Event %s from %s is still being delivered, NACKing its redelivery", event.ID(), event.Source())
+		metrics.IncInFlightDuplicateEvents()
+		return cloudevents.NewHTTPResult(http.StatusConflict, "event %s is still being delivered", event.ID())
+	}
+
+	// Only new events count towards the ingress rate, redeliveries and expired events
+	// would otherwise inflate it
+	weights.RecordArrival()
+
+	var response cloudevents.Result
+	if dispatchQueues != nil {
+		response = enqueue(ctx, event)
+	} else {
+		result := route(event)
+		if cloudevents.IsACK(result) {
+			dedup.Delivered(event)
+			metrics.ObserveEventLatency(priorityOf(event), time.Since(received))
+		}
+		response = brokerResponse(event, result, received)
+	}
+
+	if !cloudevents.IsACK(response) {
+		// The broker redelivers the event, which must not be taken for a duplicate
+		dedup.Forget(event)
+	}
+	return response
+}
+
+// Helper function to route an event with the currently selected algorithm, among the
+// services allowed by the routing rules
+func route(event cloudevents.Event) cloudevents.Result {
+	_, algorithm := routing.SelectedAlgorithm()
+	return algorithm.RouteEvent(event, rules.Select(event, db.ServicesMap))
+}
+
+// Helper function to apply the NACK_FAILURES policy to a routing result. Dropped events
+// are written to the dead-letter sink, NACKed ones are left to the broker's redelivery.
+func brokerResponse(event cloudevents.Event, result cloudevents.Result, received time.Time) cloudevents.Result {
+	if cloudevents.IsACK(result) {
+		return cloudevents.ResultACK
+	}
+
+	class := routing.ClassifyFailure(result)
+	if !config.NackFailures[class] {
+		log.Printf("🗑️ Dropping event %s after %s failure: %v", event.ID(), class, result)
+		deadLetter(event, result, received)
+		return cloudevents.ResultACK
+	}
+
+	log.Printf("↩️ NACKing event %s after %s failure: %v", event.ID(), class, result)
+	switch class {
+	case routing.FailureTimeout:
+		return cloudevents.NewHTTPResult(http.StatusGatewayTimeout, "%v", result)
+	case routing.FailureNoDestination:
+		return cloudevents.NewHTTPResult(http.StatusServiceUnavailable, "%v", result)
+	case routing.FailureTooMany:
+		return cloudevents.NewHTTPResult(http.StatusTooManyRequests, "%v", result)
+	default:
+		return cloudevents.NewHTTPResult(http.StatusBadGateway, "%v", result)
+	}
+}
+
+// Helper function to write an event that could not be delivered to the dead-letter sink.
+// The event is forgotten by the de-duplication stage so that it can be replayed.
+func deadLetter(event cloudevents.Event, result cloudevents.Result, received time.Time) {
+	record := deadletter.Record{
+		Event:      event,
+		Stage:      "load-balancer",
+		LastError:  result.Error(),
+		ReceivedAt: received,
+		FailedAt:   time.Now(),
+	}
+
+	var deliveryErr *routing.DeliveryError
+	if errors.As(result, &deliveryErr) {
+		record.Destination = deliveryErr.Destination
+		record.Attempts = deliveryErr.Attempts
+		if deliveryErr.Err != nil {
+			record.LastError = deliveryErr.Err.Error()
+		}
+	}
+
+	class := routing.ClassifyFailure(result)
+	reason := "failed"
+	if class == routing.FailureExpired {
+		reason = "expired"
+	}
+
+	deadletter.Write(record)
+	dedup.Forget(event)
+	metrics.IncDeadLettered(class)
+	metrics.IncEventsDropped(priorityOf(event), reason)
 }
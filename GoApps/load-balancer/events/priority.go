@@ -0,0 +1,40 @@
+package events
+
+import (
+	"strings"
+
+	"load-balancer/config"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+)
+
+// Priority classes carried in the PRIORITY_EXTENSION extension of an event
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// Priorities in the order dispatch workers serve them
+var priorities = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+// Helper function to read the priority class of an event, falling back to DEFAULT_PRIORITY
+// when the extension is missing or holds an unknown value
+func priorityOf(event cloudevents.Event) string {
+	value, ok := event.Extensions()[config.PriorityExtension]
+	if !ok {
+		return config.DefaultPriority
+	}
+	priority, err := types.ToString(value)
+	if err != nil {
+		return config.DefaultPriority
+	}
+
+	switch priority = strings.ToLower(priority); priority {
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return priority
+	default:
+		return config.DefaultPriority
+	}
+}
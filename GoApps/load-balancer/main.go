@@ -7,12 +7,17 @@ import (
 	"os/signal"
 	"syscall"
 
+	"load-balancer/admin"
 	"load-balancer/config"
 	"load-balancer/db"
+	"load-balancer/deadletter"
+	"load-balancer/dedup"
 	"load-balancer/events"
 	"load-balancer/metrics"
+	"load-balancer/queue"
 	"load-balancer/rabbitmq"
 	"load-balancer/routing"
+	"load-balancer/rules"
 	"load-balancer/weights"
 )
 
@@ -34,9 +39,31 @@ func main() {
 
 	// Initialize services and other components
 	db.InitializeServices(rdb)
+	weights.InitializeControlLaws()
 
-	go events.StartReceiver()
+	// Load content-based routing rules, they are validated against the services above
+	if config.RulesFile != "" {
+		if err := rules.Load(config.RulesFile); err != nil {
+			log.Fatalf("❌ Invalid routing rules: %v", err)
+		}
+	}
+
+	// Open the sink for events that cannot be delivered
+	if err := deadletter.Init(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Drop events the broker redelivers after they were already accepted
+	dedup.Init()
+
+	// In AMQP mode the prefetch bounds the events in flight instead of the dispatch queue
+	if config.IngressMode == "http" {
+		events.StartDispatcher()
+		go events.StartReceiver()
+	}
+	go admin.StartAdminServer()
 	go routing.StartAdmissionRateUpdater(rdb)
+	go weights.StartCongestionMonitor(rdb)
 
 	// Find the queue name with the specified prefix
 	queueName, err := rabbitmq.FindQueueWithPrefix("rabbitmq-setup.event-trigger.")
@@ -56,13 +83,34 @@ func main() {
 	defer conn.Close()
 	defer ch.Close()
 
+	// Consume the trigger queue directly on a channel of its own, so that its prefetch
+	// does not affect the channel used to inspect the queue
+	if config.IngressMode == "amqp" {
+		consumeCh, err := conn.Channel()
+		if err != nil {
+			log.Fatalf("❌ Failed to open a consumer channel: %v", err)
+		}
+		defer consumeCh.Close()
+
+		deliveries, err := rabbitmq.Consume(queueName, consumeCh, config.AMQPPrefetch)
+		if err != nil {
+			log.Fatalf("❌ Failed to consume queue: %v", err)
+		}
+		go func() {
+			events.StartAMQPReceiver(deliveries, config.AMQPPrefetch, consumeCh, queueName)
+			log.Fatalf("❌ AMQP delivery channel of queue %s closed", queueName)
+		}()
+	}
+
 	// Create a channel to signal termination
 	done := make(chan bool)
 
-	// Start the AMQP channel to continuously poll the queue
-	go func() {
-		rabbitmq.PollQueue(queueName, ch, done)
-	}()
+	// Watch the queue and start an empty queue event whenever it drains
+	monitor, err := queue.NewMonitor(queueName, ch)
+	if err != nil {
+		log.Fatalf("❌ Failed to create queue monitor: %v", err)
+	}
+	go weights.ConsumeQueueTransitions(queue.Watch(monitor, config.CheckInterval, done))
 
 	// Initialize and start the metrics server
 	metrics.InitMetrics()
@@ -9,8 +9,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -30,11 +32,107 @@ import (
 )
 
 var (
-	services    = []string{"service1", "service2", "service3"}
 	GammaMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "emptyqweight",
 		Help: "Gamma Metric for each service, calculated and updated every t_k event.",
 	}, []string{"service"})
+
+	LatencyEstimateMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "routing_latency_ewma_seconds",
+		Help: "Peak-sensitive EWMA of the dispatch round-trip time for each service.",
+	}, []string{"service"})
+
+	RetriesMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "routing_retries_total",
+		Help: "Number of dispatch retries, labelled with the service whose send failed.",
+	}, []string{"service"})
+
+	RetryBudgetExhaustedMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "routing_retry_budget_exhausted_total",
+		Help: "Number of retries skipped because the retry budget was exhausted.",
+	})
+
+	BreakerStateMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Circuit breaker state for each service: 0 closed, 1 open, 2 half-open.",
+	}, []string{"service"})
+
+	DispatchQueueDepthMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dispatch_queue_depth",
+		Help: "Number of received events waiting for a dispatch worker, by priority.",
+	}, []string{"priority"})
+
+	DispatchQueueWaitMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dispatch_queue_wait_seconds",
+		Help:    "Time events spend in the dispatch queue before a worker routes them, by priority.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"priority"})
+
+	DispatchRejectedMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dispatch_queue_rejected_total",
+		Help: "Number of events rejected because the dispatch queue was full (429, or 503 when blocking gave up), by priority.",
+	}, []string{"priority"})
+
+	EventLatencyMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "event_latency_seconds",
+		Help:    "Time from receiving an event to its delivery to a service, by priority.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"priority"})
+
+	EventsDroppedMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_dropped_total",
+		Help: "Number of events that were not delivered, by priority and reason.",
+	}, []string{"priority", "reason"})
+
+	EventsExpiredMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_expired_total",
+		Help: "Number of events discarded because their deadline passed, by stage.",
+	}, []string{"stage"})
+
+	DuplicateEventsMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "duplicate_events_total",
+		Help: "Number of redelivered events dropped by the de-duplication stage.",
+	})
+
+	InFlightDuplicateEventsMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "duplicate_events_in_flight_total",
+		Help: "Number of redelivered events NACKed because their first attempt was still being delivered.",
+	})
+
+	AdmissionRateLimitHitsMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "admission_rate_limit_hits_total",
+		Help: "Number of times the admission rate policy bounded a service: min, max, slew or epoch_timeout.",
+	}, []string{"service", "limit"})
+
+	CongestionSignalsMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "congestion_signals_total",
+		Help: "Number of congestion signals by service and reason (queued_requests, 429, 5xx or saturation), and whether they decreased the admission rate, were ignored during the cooldown, or left a control law that does not use epochs unaffected.",
+	}, []string{"service", "reason", "outcome"})
+
+	IngressRateMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ingress_rate",
+		Help: "EWMA of the rate of events received by the load balancer, in events per second.",
+	})
+
+	DeadLetteredMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dead_lettered_events_total",
+		Help: "Number of undeliverable events written to the dead-letter sink, by failure class.",
+	}, []string{"class"})
+
+	DispatchLatencyMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dispatch_latency_seconds",
+		Help:    "Time from sending an event to a service until it was acknowledged.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"service"})
+
+	// Dispatch latencies summed up since they were last taken, per service
+	dispatchLatencySums   = make(map[string]float64)
+	dispatchLatencyCounts = make(map[string]int)
+	dispatchLatencyMu     sync.Mutex
+
+	// Cached view of queued_requests, refreshed by StartQdReqsRefresher
+	cachedQdReqs   = make(map[string]int)
+	cachedQdReqsMu sync.RWMutex
 )
 
 func init() {
@@ -43,11 +141,26 @@ func init() {
 	prometheus.Unregister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 }
 
-// Helper function to convert internal service names to external service names
+// Namespace the consumer services are deployed in
+const consumerNamespace = "rabbitmq-setup"
+
+// Helper function to convert internal service names to the consumer's Kubernetes service name
 func externalServiceName(service string) string {
+	if s, ok := db.ServicesMap[service]; ok && s.Consumer != "" {
+		return s.Consumer
+	}
 	return strings.Replace(service, "service", "consumer-service-", 1)
 }
 
+// Helper function to list the configured service names
+func serviceNames() []string {
+	names := make([]string, 0, len(db.ServicesMap))
+	for name := range db.ServicesMap {
+		names = append(names, name)
+	}
+	return names
+}
+
 func StartMetricsServer() {
 	mux := http.NewServeMux()
 	server := &http.Server{
@@ -61,9 +174,9 @@ func StartMetricsServer() {
 
 func InitMetrics() {
 	// Register any static metrics here if needed
-	GammaMetric.WithLabelValues("service1").Set(0)
-	GammaMetric.WithLabelValues("service2").Set(0)
-	GammaMetric.WithLabelValues("service3").Set(0)
+	for _, service := range serviceNames() {
+		GammaMetric.WithLabelValues(service).Set(0)
+	}
 }
 
 func UpdateMetric(service string, value float64) {
@@ -71,15 +184,130 @@ func UpdateMetric(service string, value float64) {
 	log.Printf("Updated GammaMetric for %s to %f", service, value)
 }
 
+func UpdateLatencyEstimate(service string, seconds float64) {
+	LatencyEstimateMetric.WithLabelValues(service).Set(seconds)
+}
+
+func IncRetries(service string) {
+	RetriesMetric.WithLabelValues(service).Inc()
+}
+
+func IncRetryBudgetExhausted() {
+	RetryBudgetExhaustedMetric.Inc()
+}
+
+func UpdateBreakerState(service string, state int) {
+	BreakerStateMetric.WithLabelValues(service).Set(float64(state))
+}
+
+func UpdateDispatchQueueDepth(priority string, depth int) {
+	DispatchQueueDepthMetric.WithLabelValues(priority).Set(float64(depth))
+}
+
+func ObserveDispatchWait(priority string, wait time.Duration) {
+	DispatchQueueWaitMetric.WithLabelValues(priority).Observe(wait.Seconds())
+}
+
+func IncDispatchRejected(priority string) {
+	DispatchRejectedMetric.WithLabelValues(priority).Inc()
+}
+
+func ObserveEventLatency(priority string, latency time.Duration) {
+	EventLatencyMetric.WithLabelValues(priority).Observe(latency.Seconds())
+}
+
+func IncEventsDropped(priority, reason string) {
+	EventsDroppedMetric.WithLabelValues(priority, reason).Inc()
+}
+
+func IncEventsExpired(stage string) {
+	EventsExpiredMetric.WithLabelValues(stage).Inc()
+}
+
+func IncDuplicateEvents() {
+	DuplicateEventsMetric.Inc()
+}
+
+func IncInFlightDuplicateEvents() {
+	InFlightDuplicateEventsMetric.Inc()
+}
+
+func IncAdmissionRateLimitHits(service, limit string) {
+	AdmissionRateLimitHitsMetric.WithLabelValues(service, limit).Inc()
+}
+
+func IncCongestionSignals(service, reason, outcome string) {
+	CongestionSignalsMetric.WithLabelValues(service, reason, outcome).Inc()
+}
+
+func ObserveDispatchLatency(service string, latency time.Duration) {
+	DispatchLatencyMetric.WithLabelValues(service).Observe(latency.Seconds())
+
+	dispatchLatencyMu.Lock()
+	defer dispatchLatencyMu.Unlock()
+	dispatchLatencySums[service] += latency.Seconds()
+	dispatchLatencyCounts[service]++
+}
+
+// TakeDispatchLatency returns the mean dispatch latency of a service in seconds since
+// the previous call, and false when no event was acknowledged by it in between
+func TakeDispatchLatency(service string) (float64, bool) {
+	dispatchLatencyMu.Lock()
+	defer dispatchLatencyMu.Unlock()
+
+	count := dispatchLatencyCounts[service]
+	if count == 0 {
+		return 0, false
+	}
+	mean := dispatchLatencySums[service] / float64(count)
+	dispatchLatencySums[service], dispatchLatencyCounts[service] = 0, 0
+	return mean, true
+}
+
+func UpdateIngressRate(eventsPerSecond float64) {
+	IngressRateMetric.Set(eventsPerSecond)
+}
+
+func IncDeadLettered(class string) {
+	DeadLetteredMetric.WithLabelValues(class).Inc()
+}
+
 func FetchQdReqs() map[string]int {
 	metricType := "queued_requests"
 	metrics := make(map[string]int)
 
 	// Fetch and store metrics
-	fetchAndStoreMetrics(services, metricType, metrics)
+	fetchAndStoreMetrics(serviceNames(), metricType, metrics)
 	return metrics
 }
 
+// StartQdReqsRefresher scrapes queued_requests every interval and stores the result
+// so that routing decisions can read it without querying every consumer per event.
+func StartQdReqsRefresher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		qdReqs := FetchQdReqs()
+
+		cachedQdReqsMu.Lock()
+		cachedQdReqs = qdReqs
+		cachedQdReqsMu.Unlock()
+
+		<-ticker.C
+	}
+}
+
+// CachedQdReqs returns the last queued_requests value scraped for the service and
+// whether a value has been scraped yet.
+func CachedQdReqs(service string) (int, bool) {
+	cachedQdReqsMu.RLock()
+	defer cachedQdReqsMu.RUnlock()
+
+	value, ok := cachedQdReqs[service]
+	return value, ok
+}
+
 func FetchReplicas(service string) int {
 	externalName := externalServiceName(service)
 	replicas := FetchReplicaNum(externalName)
@@ -163,7 +391,7 @@ func fetchServiceEndpoints(service string) ([]string, error) {
 		return nil, err
 	}
 
-	pods, err := clientset.CoreV1().Pods("rabbitmq-setup").List(context.TODO(), metav1.ListOptions{
+	pods, err := clientset.CoreV1().Pods(consumerNamespace).List(context.TODO(), metav1.ListOptions{
 		LabelSelector: fmt.Sprintf("service=%s,app=event-display", externalName),
 	})
 	if err != nil {
@@ -185,31 +413,33 @@ func homeDir() string {
 	return os.Getenv("USERPROFILE") // windows
 }
 
-// Function to calculate gamma for each service
-func UpdateGamma() {
-	queued_requests := FetchQdReqs()
+// Function to calculate gamma for a single service from the baseline of its epoch. It
+// scrapes the consumer's pods, so callers must not hold db.AdmissionRatesMutex.
+func UpdateGamma(service string, emptyQWeight, beta float64, alpha int) {
+	queued_requests := make(map[string]int)
+	fetchAndStoreMetrics([]string{service}, "queued_requests", queued_requests)
+	replicas := float64(FetchReplicaNum(service))
 
-	// Calculate gamma for each service
-	for _, service := range db.ServicesMap {
-		qWeight := db.EmptyQWeights[service.Name]
-		queuedRequests := queued_requests[service.Name]
-		replicas := float64(FetchReplicaNum(service.Name))
+	gamma := (emptyQWeight*beta + math.Sqrt(replicas*float64(queued_requests[service])*2*float64(alpha)))
+	UpdateMetric(service, gamma)
+	log.Printf("🔢 Gamma for %s: %f", service, gamma)
+}
 
-		gamma := (qWeight*service.Beta + math.Sqrt(replicas*float64(queuedRequests)*2*float64(service.Alpha)))
-		UpdateMetric(service.Name, float64(gamma))
-		log.Printf("🔢 Gamma for %s: %f", service.Name, gamma)
-	}
+// Helper function to build the Prometheus pod selector of the consumer of a service. The
+// consumers are Knative services, whose pods are named <consumer>-<revision>-deployment-*.
+func consumerPodSelector(service string) string {
+	return fmt.Sprintf(`namespace="%s", pod=~"%s-[0-9]+-deployment-.*"`, consumerNamespace, regexp.QuoteMeta(externalServiceName(service)))
 }
 
 // Fetch CPU usage using Prometheus metrics for all replicas of a service
 func FetchCPUUsage(service string) float64 {
-	query := fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{namespace="$namespace", pod=~"%s-deployment-.*", container=""}[1m]))`, service)
+	query := fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{%s, container=""}[1m]))`, consumerPodSelector(service))
 	return executePrometheusQuery(query)
 }
 
 // Fetch CPU request limits for all replicas of a service
 func FetchCPULimit(service string) float64 {
-	query := fmt.Sprintf(`sum(kube_pod_container_resource_requests{resource="cpu", namespace="$namespace", pod=~"%s-deployment-.*"})`, service)
+	query := fmt.Sprintf(`sum(kube_pod_container_resource_requests{resource="cpu", %s})`, consumerPodSelector(service))
 	return executePrometheusQuery(query)
 }
 
@@ -0,0 +1,26 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// AMQPMonitor inspects the queue on an AMQP channel. It only sees the message count.
+type AMQPMonitor struct {
+	queueName string
+	ch        *amqp.Channel
+}
+
+func NewAMQPMonitor(queueName string, ch *amqp.Channel) *AMQPMonitor {
+	return &AMQPMonitor{queueName: queueName, ch: ch}
+}
+
+func (m *AMQPMonitor) State() (State, error) {
+	queue, err := m.ch.QueueInspect(m.queueName)
+	if err != nil {
+		return State{}, fmt.Errorf("❌ failed to inspect queue: %v", err)
+	}
+	return State{Messages: queue.Messages, SampledAt: time.Now()}, nil
+}
@@ -0,0 +1,53 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// FakeMonitor reports a state set by the caller, to drive the empty queue logic
+// without a broker
+type FakeMonitor struct {
+	mu    sync.Mutex
+	state State
+	err   error
+}
+
+func NewFakeMonitor() *FakeMonitor {
+	return &FakeMonitor{}
+}
+
+// SetMessages sets the message count reported by the next samples
+func (m *FakeMonitor) SetMessages(messages int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.Messages = messages
+	m.err = nil
+}
+
+// SetState sets the full state reported by the next samples
+func (m *FakeMonitor) SetState(state State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	m.err = nil
+}
+
+// Fail makes the next samples fail until a state is set again
+func (m *FakeMonitor) Fail(message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = errors.New(message)
+}
+
+func (m *FakeMonitor) State() (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return State{}, m.err
+	}
+	state := m.state
+	state.SampledAt = time.Now()
+	return state, nil
+}
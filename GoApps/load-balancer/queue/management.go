@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/parnurzeal/gorequest"
+)
+
+// ManagementMonitor reads the queue from the RabbitMQ management HTTP API, which also
+// reports publish and delivery rates. The API aggregates its statistics every few
+// seconds, so samples lag behind the AMQP monitor, and the rates keep a queue that is
+// still filling up from being reported empty.
+type ManagementMonitor struct {
+	apiURL   string
+	user     string
+	password string
+}
+
+type managementQueue struct {
+	Messages     int `json:"messages"`
+	MessageStats struct {
+		PublishDetails struct {
+			Rate float64 `json:"rate"`
+		} `json:"publish_details"`
+		DeliverGetDetails struct {
+			Rate float64 `json:"rate"`
+		} `json:"deliver_get_details"`
+	} `json:"message_stats"`
+}
+
+func NewManagementMonitor(baseURL, user, password, vhost, queueName string) *ManagementMonitor {
+	return &ManagementMonitor{
+		apiURL: fmt.Sprintf("%s/api/queues/%s/%s", strings.TrimSuffix(baseURL, "/"),
+			url.PathEscape(vhost), url.PathEscape(queueName)),
+		user:     user,
+		password: password,
+	}
+}
+
+func (m *ManagementMonitor) State() (State, error) {
+	resp, body, errs := gorequest.New().Get(m.apiURL).
+		SetBasicAuth(m.user, m.password).
+		End()
+	if len(errs) > 0 {
+		return State{}, fmt.Errorf("❌ Failed to get queue: %v", errs)
+	}
+	if resp.StatusCode != 200 {
+		return State{}, fmt.Errorf("❌ Unexpected status code: %d", resp.StatusCode)
+	}
+
+	var queue managementQueue
+	if err := json.Unmarshal([]byte(body), &queue); err != nil {
+		return State{}, fmt.Errorf("❌ Failed to parse response: %v", err)
+	}
+	return State{
+		Messages:    queue.Messages,
+		PublishRate: queue.MessageStats.PublishDetails.Rate,
+		DeliverRate: queue.MessageStats.DeliverGetDetails.Rate,
+		SampledAt:   time.Now(),
+	}, nil
+}
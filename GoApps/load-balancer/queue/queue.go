@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"load-balancer/config"
+
+	"github.com/streadway/amqp"
+)
+
+// State is a sample of the trigger queue. Rates are in messages per second and stay
+// zero for monitors that cannot measure them.
+type State struct {
+	Messages    int
+	PublishRate float64
+	DeliverRate float64
+	SampledAt   time.Time
+}
+
+// Empty reports whether the queue drained. The management API aggregates the message
+// count every few seconds, so a queue that is published to faster than it is consumed
+// is not taken for empty even if the last count was zero.
+func (s State) Empty() bool {
+	return s.Messages == 0 && s.PublishRate <= s.DeliverRate
+}
+
+// QueueMonitor samples the state of the trigger queue
+type QueueMonitor interface {
+	State() (State, error)
+}
+
+// Transition is emitted by Watch whenever the queue becomes empty or non-empty
+type Transition struct {
+	Empty bool
+	State State
+}
+
+// NewMonitor creates the monitor selected by QUEUE_MONITOR. The AMQP monitor inspects
+// the queue on ch.
+func NewMonitor(queueName string, ch *amqp.Channel) (QueueMonitor, error) {
+	switch config.QueueMonitor {
+	case "amqp":
+		return NewAMQPMonitor(queueName, ch), nil
+	case "management":
+		return NewManagementMonitor(config.RabbitMQManagementURL, config.RabbitMQUser, config.RabbitMQPass, "/", queueName), nil
+	default:
+		return nil, fmt.Errorf("unknown queue monitor %q", config.QueueMonitor)
+	}
+}
+
+// Watch samples the monitor every interval until done is signalled and emits a
+// Transition for the first sample and for every change between empty and non-empty.
+// Failed samples are logged and skipped. The returned channel is closed on done.
+func Watch(monitor QueueMonitor, interval time.Duration, done <-chan bool) <-chan Transition {
+	transitions := make(chan Transition, 1)
+
+	go func() {
+		defer close(transitions)
+		known, wasEmpty := false, false
+		for {
+			select {
+			case <-done:
+				log.Println("🛑 Stopping queue monitor")
+				return
+			default:
+			}
+
+			state, err := monitor.State()
+			if err != nil {
+				log.Printf("❌ Error checking queue: %v\n", err)
+			} else {
+				log.Printf("📋 Queue has %d messages (publish %.2f/s, deliver %.2f/s)\n",
+					state.Messages, state.PublishRate, state.DeliverRate)
+				if !known || state.Empty() != wasEmpty {
+					known, wasEmpty = true, state.Empty()
+					select {
+					case transitions <- Transition{Empty: wasEmpty, State: state}:
+					case <-done:
+						log.Println("🛑 Stopping queue monitor")
+						return
+					}
+				}
+			}
+			time.Sleep(interval)
+		}
+	}()
+
+	return transitions
+}
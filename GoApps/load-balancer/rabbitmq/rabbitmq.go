@@ -8,8 +8,6 @@ import (
 	"time"
 
 	"load-balancer/config"
-	"load-balancer/db"
-	"load-balancer/weights"
 
 	"github.com/parnurzeal/gorequest"
 	"github.com/streadway/amqp"
@@ -39,7 +37,7 @@ func SetupRabbitMQ() (*amqp.Connection, *amqp.Channel, error) {
 
 func FindQueueWithPrefix(prefix string) (string, error) {
 	request := gorequest.New()
-	apiURL := `http://rabbitmq.rabbitmq-setup.svc.cluster.local:15672/api/queues`
+	apiURL := config.RabbitMQManagementURL + "/api/queues"
 	resp, body, errs := request.Get(apiURL).
 		SetBasicAuth(config.RabbitMQUser, config.RabbitMQPass).
 		End()
@@ -67,36 +65,33 @@ func FindQueueWithPrefix(prefix string) (string, error) {
 	return "", nil // Queue with the specified prefix not found
 }
 
-func PollQueue(queueName string, ch *amqp.Channel, done chan bool) {
-	log.Printf("📡 Starting to poll queue %s", queueName)
-	for {
-		select {
-		case <-done:
-			log.Println("🛑 Stopping pollQueue goroutine")
-			return
-		default:
-			messageCount, err := CheckQueue(queueName, ch)
-			if err != nil {
-				log.Printf("❌ Error checking queue: %v\n", err)
-			} else {
-				log.Printf("📋 Queue %s has %d messages\n", queueName, messageCount)
-				if messageCount == 0 {
-					//log.Printf("📭 Queue %s is now empty\n", queueName)
-					weights.UpdateEmptyQWeightRoutine()
-				} else if messageCount > 0 {
-					db.PrevQueueEmpty = false
-				}
-			}
-			time.Sleep(config.CheckInterval)
-		}
+// Consume starts consuming a queue with manual acknowledgement, allowing up to
+// prefetch unacknowledged messages on the channel
+func Consume(queueName string, ch *amqp.Channel, prefetch int) (<-chan amqp.Delivery, error) {
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("❌ failed to set prefetch: %v", err)
+	}
+
+	deliveries, err := ch.Consume(queueName, "", false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("❌ failed to consume queue %s: %v", queueName, err)
 	}
+	log.Printf("📥 Consuming queue %s with prefetch %d", queueName, prefetch)
+	return deliveries, nil
 }
 
-func CheckQueue(queueName string, ch *amqp.Channel) (int, error) {
-	log.Printf("🔍 Checking queue: %s", queueName)
-	queue, err := ch.QueueInspect(queueName)
+// DeclareRetryQueue declares a queue that holds messages for delay and then dead-letters
+// them back to queueName through the default exchange. Its name does not share the
+// prefix of the trigger queues, so FindQueueWithPrefix never picks it up.
+func DeclareRetryQueue(ch *amqp.Channel, queueName string, delay time.Duration) (string, error) {
+	retryQueue := fmt.Sprintf("lb-retry.%s.%d", queueName, delay.Milliseconds())
+	_, err := ch.QueueDeclare(retryQueue, true, false, false, false, amqp.Table{
+		"x-message-ttl":             delay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queueName,
+	})
 	if err != nil {
-		return 0, fmt.Errorf("❌ failed to inspect queue: %v", err)
+		return "", fmt.Errorf("❌ failed to declare retry queue %s: %v", retryQueue, err)
 	}
-	return queue.Messages, nil
+	return retryQueue, nil
 }
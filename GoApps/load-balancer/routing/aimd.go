@@ -1,90 +1,37 @@
 package routing
 
 import (
-	"context"
-	"fmt"
 	"log"
-	"math/rand"
-	"sort"
-	"time"
 
 	rdb "load-balancer/db"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 )
 
-type AIMDRoutingAlgorithm struct{}
-
-// Helper function to generate prefix sums
-func generatePrefixSums(servicesMap map[string]*rdb.Service) []float64 {
-	prefixSums := make([]float64, len(servicesMap))
-	sum := 0.0
-	index := 0
-	for _, service := range servicesMap {
-		sum += service.CurrWeight
-		prefixSums[index] = sum
-		index++
-	}
-	return prefixSums
-}
-
-// Binary search to find the appropriate service based on the random value
-func binarySearch(prefixSums []float64, randomValue float64) int {
-	return sort.Search(len(prefixSums), func(i int) bool {
-		return prefixSums[i] > randomValue
-	})
+// AIMDRoutingAlgorithm splits events across services in proportion to the CurrWeight
+// values maintained by the AIMD weight updater. Selector decides how the split is
+// realised; a nil Selector picks at random.
+type AIMDRoutingAlgorithm struct {
+	Selector WeightedSelector
 }
 
-func (a *AIMDRoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) {
-
-	// Generate prefix sums for the services' weights
-	prefixSums := generatePrefixSums(servicesMap)
-	totalWeight := prefixSums[len(prefixSums)-1]
-
-	// Log the prefix sums and total weight
-	log.Printf("🧮 Prefix sums: %v, Total weight: %.2f", prefixSums, totalWeight)
-
-	// Generate a random float64 value between 0 and the total sum of weights
-	randomValue := rand.Float64() * totalWeight
-	log.Printf("🎲 Generated random value: %.2f", randomValue)
+func (a *AIMDRoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) cloudevents.Result {
 
-	// Use binary search to find the selected service index
-	selectedIndex := binarySearch(prefixSums, randomValue)
-	log.Printf("🎯 Selected service index: %d", selectedIndex)
+	// Take a sorted snapshot so the weights and the selected service agree
+	table := NewRoutingTable(servicesMap)
+	log.Printf("🧮 Routing table weights: %v, Total weight: %.2f", table.Weights, table.TotalWeight())
 
-	// Retrieve the selected service based on the index
-	var destination *rdb.Service
-	i := 0
-	for _, service := range servicesMap {
-		if i == selectedIndex {
-			destination = service
-			break
-		}
-		i++
+	selector := a.Selector
+	if selector == nil {
+		selector = &RandomWeightedSelector{}
 	}
 
+	destination := selector.Select(table)
 	if destination == nil {
 		log.Println("❌ Error: Destination is empty. Admission rate selection failed.")
-		return
-	}
-
-	destinationURL := fmt.Sprintf("http://%s.rabbitmq-setup.svc.cluster.local", destination.Name)
-	c, err := cloudevents.NewClientHTTP()
-	if err != nil {
-		log.Printf("❌ Failed to create client: %v", err)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	ctx = cloudevents.ContextWithTarget(ctx, destinationURL)
-	log.Printf("☁️ Sending CloudEvent to %s", destination.Name)
-
-	if result := c.Send(ctx, event); !cloudevents.IsACK(result) {
-		log.Printf("❌ Failed to send: %v", result)
-		return
+		return ErrNoDestination
 	}
+	log.Printf("🎯 Selected service: %s", destination.Name)
 
-	log.Printf("✅ Successfully sent event to %s", destination.Name)
+	return deliver(event, table, destination, sendEvent)
 }
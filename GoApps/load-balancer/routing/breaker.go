@@ -0,0 +1,235 @@
+package routing
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"load-balancer/config"
+	"load-balancer/metrics"
+)
+
+// BreakerState is the state of a per-service circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrCircuitOpen is returned when a destination was skipped because its breaker is open.
+// It wraps ErrNoDestination so the broker is asked to redeliver the event later.
+var ErrCircuitOpen = fmt.Errorf("circuit open: %w", ErrNoDestination)
+
+var (
+	breakers   = make(map[string]*CircuitBreaker)
+	breakersMu sync.Mutex
+)
+
+// CircuitBreaker guards a single service. It opens after BREAKER_CONSECUTIVE_FAILURES
+// failed sends in a row, or once the error rate over the current BREAKER_WINDOW exceeds
+// BREAKER_ERROR_RATE with at least BREAKER_MIN_REQUESTS sends. After BREAKER_OPEN_DURATION
+// it lets up to BREAKER_HALF_OPEN_PROBES concurrent probes through; that many successful
+// probes close it again and a single failed probe re-opens it.
+type CircuitBreaker struct {
+	mu                  sync.Mutex
+	service             string
+	state               BreakerState
+	consecutiveFailures int
+	windowStart         time.Time
+	windowRequests      int
+	windowFailures      int
+	openedAt            time.Time
+	probesInFlight      int
+	probeSuccesses      int
+}
+
+// BreakerStatus is a point-in-time view of a breaker, as reported by the admin API
+type BreakerStatus struct {
+	Service             string     `json:"service"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	WindowRequests      int        `json:"window_requests"`
+	WindowFailures      int        `json:"window_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// Helper function to get or lazily create the breaker of a service
+func breakerFor(service string) *CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	breaker, ok := breakers[service]
+	if !ok {
+		breaker = &CircuitBreaker{service: service, windowStart: time.Now()}
+		breakers[service] = breaker
+		metrics.UpdateBreakerState(service, int(BreakerClosed))
+	}
+	return breaker
+}
+
+// Breakers returns the status of every breaker sorted by service name
+func Breakers() []BreakerStatus {
+	breakersMu.Lock()
+	all := make([]*CircuitBreaker, 0, len(breakers))
+	for _, breaker := range breakers {
+		all = append(all, breaker)
+	}
+	breakersMu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(all))
+	for _, breaker := range all {
+		statuses = append(statuses, breaker.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Service < statuses[j].Service
+	})
+	return statuses
+}
+
+// ResetBreaker force-closes the breaker of a service
+func ResetBreaker(service string) error {
+	breakersMu.Lock()
+	breaker, ok := breakers[service]
+	breakersMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no circuit breaker for service %s", service)
+	}
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.transition(BreakerClosed, time.Now())
+	return nil
+}
+
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Service:             b.service,
+		State:               b.state.String(),
+		ConsecutiveFailures: b.consecutiveFailures,
+		WindowRequests:      b.windowRequests,
+		WindowFailures:      b.windowFailures,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// Available reports whether the service may currently receive traffic, without
+// reserving a half-open probe.
+func (b *CircuitBreaker) Available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.checkOpenExpired(now)
+	return b.state == BreakerClosed || (b.state == BreakerHalfOpen && b.probesInFlight < config.BreakerHalfOpenProbes)
+}
+
+// Acquire reports whether a send to the service may proceed. In the half-open state it
+// reserves one of the probe slots, which is released by the matching Record call.
+func (b *CircuitBreaker) Acquire(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.checkOpenExpired(now)
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if b.probesInFlight < config.BreakerHalfOpenProbes {
+			b.probesInFlight++
+			return true
+		}
+	}
+	return false
+}
+
+// Record feeds the outcome of an acquired send back into the breaker
+func (b *CircuitBreaker) Record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probesInFlight = max(0, b.probesInFlight-1)
+		if !success {
+			b.transition(BreakerOpen, now)
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= config.BreakerHalfOpenProbes {
+			b.transition(BreakerClosed, now)
+		}
+
+	case BreakerClosed:
+		if now.Sub(b.windowStart) >= config.BreakerWindow {
+			b.windowStart = now
+			b.windowRequests = 0
+			b.windowFailures = 0
+		}
+		b.windowRequests++
+		if success {
+			b.consecutiveFailures = 0
+			return
+		}
+		b.windowFailures++
+		b.consecutiveFailures++
+
+		errorRate := float64(b.windowFailures) / float64(b.windowRequests)
+		if b.consecutiveFailures >= config.BreakerConsecutiveFailures ||
+			(b.windowRequests >= config.BreakerMinRequests && errorRate >= config.BreakerErrorRate) {
+			log.Printf("🚨 Tripping circuit breaker for %s: consecutive failures=%d, error rate=%.2f",
+				b.service, b.consecutiveFailures, errorRate)
+			b.transition(BreakerOpen, now)
+		}
+	}
+}
+
+// Helper function to move an open breaker to half-open once its open duration elapsed
+func (b *CircuitBreaker) checkOpenExpired(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= config.BreakerOpenDuration {
+		b.transition(BreakerHalfOpen, now)
+	}
+}
+
+// Helper function to switch state and reset the counters of the new state. Callers hold b.mu.
+func (b *CircuitBreaker) transition(state BreakerState, now time.Time) {
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+	if state == BreakerClosed {
+		b.windowStart = now
+		b.windowRequests = 0
+		b.windowFailures = 0
+		b.consecutiveFailures = 0
+	}
+	b.probesInFlight = 0
+	b.probeSuccesses = 0
+
+	if b.state != state {
+		log.Printf("🔌 Circuit breaker for %s: %s -> %s", b.service, b.state, state)
+	}
+	b.state = state
+	metrics.UpdateBreakerState(b.service, int(state))
+}
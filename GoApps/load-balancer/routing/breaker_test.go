@@ -0,0 +1,124 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"load-balancer/config"
+)
+
+// Step of a breaker scenario: at the offset from the start, "ok" and "fail" record the
+// outcome of a send, "acquire" expects a send to be let through and "deny" expects it to
+// be refused. want is the state after the step.
+type breakerStep struct {
+	at   time.Duration
+	op   string
+	want BreakerState
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{"consecutive failures open", []breakerStep{
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerOpen},
+			{time.Second, "deny", BreakerOpen},
+		}},
+		{"success resets the streak", []breakerStep{
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "ok", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerClosed},
+		}},
+		{"error rate opens", []breakerStep{
+			{0, "ok", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "ok", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "ok", BreakerClosed},
+			{0, "fail", BreakerOpen},
+		}},
+		{"error rate counts per window", []breakerStep{
+			{0, "fail", BreakerClosed},
+			{0, "ok", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "ok", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{10 * time.Second, "ok", BreakerClosed},
+			{10 * time.Second, "fail", BreakerClosed},
+		}},
+		{"open turns half-open after the open duration", []breakerStep{
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerOpen},
+			{4 * time.Second, "deny", BreakerOpen},
+			{5 * time.Second, "acquire", BreakerHalfOpen},
+		}},
+		{"half-open limits the probes", []breakerStep{
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerOpen},
+			{5 * time.Second, "acquire", BreakerHalfOpen},
+			{5 * time.Second, "acquire", BreakerHalfOpen},
+			{5 * time.Second, "deny", BreakerHalfOpen},
+		}},
+		{"successful probes close", []breakerStep{
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerOpen},
+			{5 * time.Second, "acquire", BreakerHalfOpen},
+			{5 * time.Second, "ok", BreakerHalfOpen},
+			{5 * time.Second, "acquire", BreakerHalfOpen},
+			{5 * time.Second, "ok", BreakerClosed},
+			{5 * time.Second, "fail", BreakerClosed},
+		}},
+		{"failed probe reopens", []breakerStep{
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerClosed},
+			{0, "fail", BreakerOpen},
+			{5 * time.Second, "acquire", BreakerHalfOpen},
+			{5 * time.Second, "fail", BreakerOpen},
+			{9 * time.Second, "deny", BreakerOpen},
+			{10 * time.Second, "acquire", BreakerHalfOpen},
+		}},
+	}
+
+	consecutive, errorRate, minRequests := config.BreakerConsecutiveFailures, config.BreakerErrorRate, config.BreakerMinRequests
+	window, openDuration, probes := config.BreakerWindow, config.BreakerOpenDuration, config.BreakerHalfOpenProbes
+	t.Cleanup(func() {
+		config.BreakerConsecutiveFailures, config.BreakerErrorRate, config.BreakerMinRequests = consecutive, errorRate, minRequests
+		config.BreakerWindow, config.BreakerOpenDuration, config.BreakerHalfOpenProbes = window, openDuration, probes
+	})
+	config.BreakerConsecutiveFailures = 3
+	config.BreakerErrorRate = 0.5
+	config.BreakerMinRequests = 6
+	config.BreakerWindow = 10 * time.Second
+	config.BreakerOpenDuration = 5 * time.Second
+	config.BreakerHalfOpenProbes = 2
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			breaker := &CircuitBreaker{service: "breaker-test", windowStart: start}
+
+			for i, step := range tt.steps {
+				now := start.Add(step.at)
+				switch step.op {
+				case "ok", "fail":
+					breaker.Record(step.op == "ok", now)
+				case "acquire", "deny":
+					if got := breaker.Acquire(now); got != (step.op == "acquire") {
+						t.Fatalf("step %d: Acquire at %v = %v", i, step.at, got)
+					}
+				}
+				if got := breaker.Status().State; got != step.want.String() {
+					t.Fatalf("step %d (%s at %v): state %s, want %s", i, step.op, step.at, got, step.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package routing
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"load-balancer/config"
+	rdb "load-balancer/db"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"golang.org/x/net/http2"
+)
+
+var (
+	// One CloudEvents client per destination, all sharing dispatchTransport so that
+	// connections are pooled and kept alive across events
+	clients   = make(map[string]cloudevents.Client)
+	clientsMu sync.Mutex
+
+	dispatchTransport     http.RoundTripper
+	dispatchTransportOnce sync.Once
+)
+
+// Helper function to build the transport used for every dispatch. With DISPATCH_H2C
+// the consumers are spoken to over cleartext HTTP/2, multiplexing all events to a
+// destination over a single connection.
+func newDispatchTransport() http.RoundTripper {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if config.DispatchH2C {
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			ReadIdleTimeout: config.DispatchIdleConnTimeout,
+		}
+	}
+
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        0, // No global limit, bounded per host below
+		MaxIdleConnsPerHost: config.DispatchMaxIdleConnsPerHost,
+		IdleConnTimeout:     config.DispatchIdleConnTimeout,
+		DisableKeepAlives:   config.DispatchDisableKeepAlives,
+	}
+}
+
+// Helper function to get or lazily create the pooled client of a destination
+func clientFor(destination *rdb.Service) (cloudevents.Client, error) {
+	dispatchTransportOnce.Do(func() {
+		dispatchTransport = newDispatchTransport()
+	})
+
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if c, ok := clients[destination.Name]; ok {
+		return c, nil
+	}
+
+	destinationURL := destination.TargetURL()
+	options := []cehttp.Option{
+		cehttp.WithTarget(destinationURL),
+		cehttp.WithRoundTripper(dispatchTransport),
+	}
+	for key, value := range destination.Headers {
+		options = append(options, cehttp.WithHeader(key, value))
+	}
+
+	c, err := cloudevents.NewClientHTTP(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	clients[destination.Name] = c
+	log.Printf("🔗 Created pooled client for %s (%s)", destination.Name, destinationURL)
+	return c, nil
+}
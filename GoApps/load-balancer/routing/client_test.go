@@ -0,0 +1,63 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rdb "load-balancer/db"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// Helper function to build the event sent by the dispatch tests and benchmarks
+func newTestEvent() cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID("benchmark")
+	event.SetSource("load-balancer/benchmark")
+	event.SetType("benchmark")
+	event.SetData(cloudevents.ApplicationJSON, map[string]string{"payload": "benchmark"})
+	return event
+}
+
+// BenchmarkDispatch compares creating a client for every event, as dispatch did before
+// clients were pooled, against the pooled client of the destination
+func BenchmarkDispatch(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	event := newTestEvent()
+	send := func(b *testing.B, c cloudevents.Client) {
+		if result := c.Send(context.Background(), event); !cloudevents.IsACK(result) {
+			b.Fatalf("send failed: %v", result)
+		}
+	}
+
+	b.Run("PerCallClient", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c, err := cloudevents.NewClientHTTP(cloudevents.WithTarget(server.URL))
+				if err != nil {
+					b.Fatal(err)
+				}
+				send(b, c)
+			}
+		})
+	})
+
+	b.Run("PooledClient", func(b *testing.B) {
+		destination := &rdb.Service{Name: "benchmark", URL: server.URL}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c, err := clientFor(destination)
+				if err != nil {
+					b.Fatal(err)
+				}
+				send(b, c)
+			}
+		})
+	})
+}
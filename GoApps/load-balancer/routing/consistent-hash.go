@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"load-balancer/config"
+	rdb "load-balancer/db"
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+)
+
+// ConsistentHashRoutingAlgorithm maps events carrying the configured affinity extension
+// onto a weighted consistent-hash ring, so that events sharing a key land on the same
+// service. Events without the key are handed to the Fallback algorithm.
+type ConsistentHashRoutingAlgorithm struct {
+	Fallback RoutingAlgorithm
+
+	mu      sync.Mutex
+	ring    []ringPoint
+	weights map[string]float64
+}
+
+type ringPoint struct {
+	hash    uint64
+	service string
+}
+
+// Helper function to hash ring points and keys onto the ring. FNV-1a spreads keys that
+// only differ in their last characters, like the points of a service, over a narrow
+// range, so its result is mixed with the splitmix64 finalizer.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Helper function to check whether the weights changed since the ring was built
+func (h *ConsistentHashRoutingAlgorithm) weightsChanged(table *RoutingTable) bool {
+	if h.weights == nil || len(h.weights) != table.Len() {
+		return true
+	}
+	for i, service := range table.Services {
+		weight, ok := h.weights[service.Name]
+		if !ok || weight != table.Weights[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to (re)build the ring. Each service owns a number of points
+// proportional to its share of the total weight; point i of a service always hashes to
+// the same position, so a weight change only adds or removes points of the services
+// whose share changed and only the keys covered by those points move.
+func (h *ConsistentHashRoutingAlgorithm) buildRing(table *RoutingTable) {
+	totalWeight := table.TotalWeight()
+
+	h.ring = h.ring[:0]
+	h.weights = make(map[string]float64, table.Len())
+	for i, service := range table.Services {
+		name := service.Name
+		weight := table.Weights[i]
+		h.weights[name] = weight
+
+		points := 1
+		if totalWeight > 0 {
+			if weight <= 0 {
+				continue
+			}
+			points = max(1, int(math.Round(weight/totalWeight*float64(config.HashRingSize))))
+		}
+		for i := 0; i < points; i++ {
+			h.ring = append(h.ring, ringPoint{
+				hash:    hashKey(fmt.Sprintf("%s#%d", name, i)),
+				service: name,
+			})
+		}
+	}
+
+	sort.Slice(h.ring, func(i, j int) bool {
+		if h.ring[i].hash == h.ring[j].hash {
+			return h.ring[i].service < h.ring[j].service
+		}
+		return h.ring[i].hash < h.ring[j].hash
+	})
+	log.Printf("💍 Rebuilt consistent-hash ring with %d points for weights %v", len(h.ring), h.weights)
+}
+
+// Helper function to find the service owning the first ring point at or after the key
+func (h *ConsistentHashRoutingAlgorithm) lookup(key string) string {
+	keyHash := hashKey(key)
+	index := sort.Search(len(h.ring), func(i int) bool {
+		return h.ring[i].hash >= keyHash
+	})
+	if index == len(h.ring) {
+		index = 0
+	}
+	return h.ring[index].service
+}
+
+func (h *ConsistentHashRoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) cloudevents.Result {
+	value, ok := event.Extensions()[config.AffinityExtension]
+	if !ok {
+		return h.Fallback.RouteEvent(event, servicesMap)
+	}
+	key, err := types.ToString(value)
+	if err != nil || key == "" {
+		log.Printf("⚠️ Ignoring unusable %s extension: %v", config.AffinityExtension, value)
+		return h.Fallback.RouteEvent(event, servicesMap)
+	}
+
+	// Services removed from the table by their breaker drop out of the ring, which
+	// only moves the keys they owned
+	table := NewRoutingTable(servicesMap)
+
+	h.mu.Lock()
+	if h.weightsChanged(table) {
+		h.buildRing(table)
+	}
+	var destination *rdb.Service
+	if len(h.ring) > 0 {
+		destination = servicesMap[h.lookup(key)]
+	}
+	h.mu.Unlock()
+
+	if destination == nil {
+		log.Println("❌ Error: Destination is empty. Consistent-hash selection failed.")
+		return ErrNoDestination
+	}
+	log.Printf("🔑 Key %q mapped to %s", key, destination.Name)
+
+	return deliver(event, table, destination, sendEvent)
+}
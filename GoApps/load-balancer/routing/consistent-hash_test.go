@@ -0,0 +1,124 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// Helper function to map the keys key-0 to key-<n-1> onto a ring built from the weights,
+// returning the service of every key and the number of ring points of every service
+func placeKeys(weights map[string]float64, n int) ([]string, map[string]int) {
+	h := &ConsistentHashRoutingAlgorithm{}
+	h.buildRing(newTestTable(weights))
+
+	points := make(map[string]int)
+	for _, point := range h.ring {
+		points[point.service]++
+	}
+	placed := make([]string, n)
+	for i := range placed {
+		placed[i] = h.lookup(fmt.Sprintf("key-%d", i))
+	}
+	return placed, points
+}
+
+func TestConsistentHashPlacementFollowsWeights(t *testing.T) {
+	tests := []map[string]float64{
+		{"a": 1, "b": 1, "c": 1},
+		{"a": 10, "b": 30, "c": 60},
+		{"a": 90, "b": 10},
+	}
+
+	const keys = 50000
+	for _, weights := range tests {
+		total := 0.0
+		for _, weight := range weights {
+			total += weight
+		}
+
+		observed := make(map[string]int)
+		placed, _ := placeKeys(weights, keys)
+		for _, service := range placed {
+			observed[service]++
+		}
+		for name, weight := range weights {
+			share := float64(observed[name]) / keys
+			if want := weight / total; math.Abs(share-want) > 0.05 {
+				t.Errorf("weights %v: %s owns %.3f of the keys, want %.3f", weights, name, share, want)
+			}
+		}
+	}
+}
+
+// Helper function to get the share of the total weight that changed hands between two
+// sets of weights, the least share of keys that has to move
+func shareShift(before, after map[string]float64) float64 {
+	shares := func(weights map[string]float64) map[string]float64 {
+		total := 0.0
+		for _, weight := range weights {
+			total += weight
+		}
+		result := make(map[string]float64, len(weights))
+		for name, weight := range weights {
+			result[name] = weight / total
+		}
+		return result
+	}
+
+	sharesBefore, sharesAfter := shares(before), shares(after)
+	shift := 0.0
+	for name := range before {
+		shift += math.Abs(sharesAfter[name] - sharesBefore[name])
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			shift += sharesAfter[name]
+		}
+	}
+	return shift / 2
+}
+
+func TestConsistentHashMovesFewKeysOnWeightChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		before map[string]float64
+		after  map[string]float64
+	}{
+		{"unchanged weights", map[string]float64{"a": 20, "b": 30, "c": 50}, map[string]float64{"a": 20, "b": 30, "c": 50}},
+		{"weight raised", map[string]float64{"a": 20, "b": 30, "c": 50}, map[string]float64{"a": 20, "b": 30, "c": 60}},
+		{"weight lowered", map[string]float64{"a": 20, "b": 30, "c": 50}, map[string]float64{"a": 20, "b": 20, "c": 50}},
+		{"service removed", map[string]float64{"a": 20, "b": 30, "c": 50}, map[string]float64{"a": 20, "c": 50}},
+		{"service added", map[string]float64{"a": 20, "b": 30}, map[string]float64{"a": 20, "b": 30, "d": 50}},
+	}
+
+	const keys = 10000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, pointsBefore := placeKeys(tt.before, keys)
+			after, pointsAfter := placeKeys(tt.after, keys)
+
+			moved := 0
+			for i := range before {
+				if before[i] == after[i] {
+					continue
+				}
+				moved++
+				// Point i of a service always hashes to the same position, so a key can
+				// only move away from a service that lost points or to one that gained some
+				from, to := before[i], after[i]
+				if pointsAfter[from] >= pointsBefore[from] && pointsAfter[to] <= pointsBefore[to] {
+					t.Fatalf("key-%d moved from %s (%d -> %d points) to %s (%d -> %d points)", i,
+						from, pointsBefore[from], pointsAfter[from], to, pointsBefore[to], pointsAfter[to])
+				}
+			}
+
+			// Keys move roughly in proportion to the shifted weight, the ring only
+			// approximates the shares so some slack is allowed
+			shift := shareShift(tt.before, tt.after)
+			if share := float64(moved) / keys; (shift == 0 && moved > 0) || share > shift+0.1 {
+				t.Fatalf("%.3f of the keys moved for a weight shift of %.3f", share, shift)
+			}
+		})
+	}
+}
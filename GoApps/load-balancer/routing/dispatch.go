@@ -0,0 +1,260 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"load-balancer/config"
+	rdb "load-balancer/db"
+	"load-balancer/deadline"
+	"load-balancer/metrics"
+	"load-balancer/weights"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+)
+
+// Failure classes reported by ClassifyFailure
+const (
+	FailureTimeout       = "timeout"
+	FailureNetwork       = "network"
+	FailureTooMany       = "429"
+	FailureClient        = "4xx"
+	FailureServer        = "5xx"
+	FailureNoDestination = "no-destination"
+	FailureExpired       = "expired"
+)
+
+// ErrNoDestination is returned when an algorithm cannot select any service for an event
+var ErrNoDestination = errors.New("no destination available")
+
+// ErrExpired is reported for events whose deadline passed before they could be delivered
+var ErrExpired = errors.New("event deadline exceeded")
+
+// Shared budget limiting retries to a fraction of all routed events
+var retries = &retryBudget{}
+
+// DeliveryError reports an event that could not be delivered after all attempts
+type DeliveryError struct {
+	Destination string // Last service the event was sent to
+	Attempts    int
+	Err         error // Result of the last attempt
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("delivery to %s failed after %d attempt(s): %v", e.Destination, e.Attempts, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// sendFunc sends an event to a single service, see sendEvent
+type sendFunc func(event cloudevents.Event, destination *rdb.Service) cloudevents.Result
+
+// retryBudget is a token bucket: every routed event deposits RETRY_BUDGET_RATIO tokens
+// and every retry withdraws one, so retries stay below that fraction of the traffic.
+// The bucket is filled at startup and never holds more than RETRY_BUDGET_RESERVE tokens.
+type retryBudget struct {
+	mu      sync.Mutex
+	balance float64
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balance = min(b.balance+config.RetryBudgetRatio, float64(config.RetryBudgetReserve))
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Allow for rounding, ten deposits of 0.2 add up to slightly less than 2
+	if b.balance < 1-1e-9 {
+		return false
+	}
+	b.balance--
+	return true
+}
+
+// Helper function to send an event to a service and wait for its acknowledgement.
+// It returns nil when the service ACKed the event and the failed result otherwise.
+func sendEvent(event cloudevents.Event, destination *rdb.Service) cloudevents.Result {
+	c, err := clientFor(destination)
+	if err != nil {
+		log.Printf("❌ Failed to create client: %v", err)
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	// Never wait for the service beyond the deadline of the event
+	ctx, cancel := context.WithTimeout(context.Background(), config.DispatchTimeout)
+	defer cancel()
+	if expiry, ok := deadline.Of(event); ok {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, expiry)
+		defer cancelDeadline()
+	}
+
+	log.Printf("☁️ Sending CloudEvent to %s", destination.Name)
+
+	start := time.Now()
+	if result := c.Send(ctx, event); !cloudevents.IsACK(result) {
+		log.Printf("❌ Failed to send to %s: %v", destination.Name, result)
+		return result
+	}
+	// Only acknowledged sends count, failures are congestion signals of their own
+	metrics.ObserveDispatchLatency(destination.Name, time.Since(start))
+
+	log.Printf("✅ Successfully sent event to %s", destination.Name)
+	return nil
+}
+
+// Helper function to deliver an event, starting with the selected destination. Failed
+// sends are retried against the next-best service by weight that has not been tried yet,
+// with exponential backoff, until RETRY_MAX_ATTEMPTS or the retry budget is exhausted.
+// Destinations whose circuit breaker refuses the send are skipped.
+// Client errors (4xx other than 429) are not retried since another consumer would
+// reject the event as well. Once the deadline of the event passed, or would pass during
+// the backoff, delivery stops with ErrExpired.
+func deliver(event cloudevents.Event, table *RoutingTable, destination *rdb.Service, send sendFunc) cloudevents.Result {
+	retries.deposit()
+
+	expiry, hasDeadline := deadline.Of(event)
+	expiredAt := func(t time.Time) bool {
+		return hasDeadline && !t.Before(expiry)
+	}
+
+	tried := make(map[string]bool)
+	backoff := config.RetryBackoff
+	attempts := 0
+	var failure *DeliveryError
+	expiredFailure := func() *DeliveryError {
+		log.Printf("⌛ Event %s expired during delivery after %d attempt(s)", event.ID(), attempts)
+		metrics.IncEventsExpired("dispatch")
+		if failure == nil {
+			return &DeliveryError{Destination: destination.Name, Attempts: attempts, Err: ErrExpired}
+		}
+		err := fmt.Errorf("%w, last attempt: %v", ErrExpired, failure.Err)
+		return &DeliveryError{Destination: failure.Destination, Attempts: attempts, Err: err}
+	}
+
+	for {
+		if expiredAt(time.Now()) {
+			return expiredFailure()
+		}
+
+		breaker := breakerFor(destination.Name)
+		if !breaker.Acquire(time.Now()) {
+			// The breaker opened after the routing table was taken, skip without backoff
+			log.Printf("🚧 Circuit for %s is open, skipping it", destination.Name)
+			tried[destination.Name] = true
+			if failure == nil {
+				failure = &DeliveryError{Destination: destination.Name, Attempts: attempts, Err: ErrCircuitOpen}
+			}
+			if destination = nextBestService(table, tried); destination == nil {
+				return failure
+			}
+			continue
+		}
+
+		attempts++
+		result := send(event, destination)
+		class := ""
+		if result != nil {
+			class = ClassifyFailure(result)
+		}
+
+		// A client error means the service answered and a timeout caused by the event
+		// deadline says nothing about the service, both only count against the event
+		expiredDuringSend := result != nil && expiredAt(time.Now())
+		breaker.Record(result == nil || class == FailureClient || (class == FailureTimeout && expiredDuringSend), time.Now())
+		if result == nil {
+			return nil
+		}
+		tried[destination.Name] = true
+
+		// Overload answers decrease the admission rate of this service only
+		if class == FailureTooMany {
+			weights.SignalCongestion(destination.Name, weights.CongestionTooMany)
+		} else if class == FailureServer {
+			weights.SignalCongestion(destination.Name, weights.CongestionServer)
+		}
+
+		failure = &DeliveryError{Destination: destination.Name, Attempts: attempts, Err: result}
+		if expiredDuringSend {
+			return expiredFailure()
+		}
+		if class == FailureClient || attempts >= config.RetryMaxAttempts {
+			return failure
+		}
+
+		next := nextBestService(table, tried)
+		if next == nil {
+			return failure
+		}
+		if expiredAt(time.Now().Add(backoff)) {
+			return expiredFailure()
+		}
+		if !retries.withdraw() {
+			log.Printf("⚠️ Retry budget exhausted, not retrying event %s", event.ID())
+			metrics.IncRetryBudgetExhausted()
+			return failure
+		}
+
+		log.Printf("🔁 Retrying event %s on %s in %v (attempt %d)", event.ID(), next.Name, backoff, attempts+1)
+		metrics.IncRetries(destination.Name)
+		time.Sleep(backoff)
+
+		backoff = min(2*backoff, config.RetryMaxBackoff)
+		destination = next
+	}
+}
+
+// Helper function to find the highest weighted service that was not tried yet
+func nextBestService(table *RoutingTable, tried map[string]bool) *rdb.Service {
+	var next *rdb.Service
+	nextWeight := 0.0
+	for i, service := range table.Services {
+		if tried[service.Name] {
+			continue
+		}
+		if next == nil || table.Weights[i] > nextWeight {
+			next = service
+			nextWeight = table.Weights[i]
+		}
+	}
+	return next
+}
+
+// ClassifyFailure maps a failed routing result onto one of the Failure* classes
+func ClassifyFailure(result cloudevents.Result) string {
+	if errors.Is(result, ErrNoDestination) {
+		return FailureNoDestination
+	}
+	if errors.Is(result, ErrExpired) {
+		return FailureExpired
+	}
+
+	var httpResult *cehttp.Result
+	if cloudevents.ResultAs(result, &httpResult) {
+		switch {
+		case httpResult.StatusCode == 429:
+			return FailureTooMany
+		case httpResult.StatusCode >= 500:
+			return FailureServer
+		case httpResult.StatusCode >= 400:
+			return FailureClient
+		}
+	}
+
+	var netErr net.Error
+	if errors.Is(result, context.DeadlineExceeded) || (errors.As(result, &netErr) && netErr.Timeout()) {
+		return FailureTimeout
+	}
+	return FailureNetwork
+}
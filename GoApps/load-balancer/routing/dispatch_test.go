@@ -0,0 +1,145 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"load-balancer/config"
+	rdb "load-balancer/db"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+func TestDeliverStopsRetryingExpiredEvents(t *testing.T) {
+	table := newTestTable(map[string]float64{"expiry-a": 50, "expiry-b": 50})
+
+	event := newTestEvent()
+	event.SetExtension(config.DeadlineExtension, time.Now().Add(20*time.Millisecond))
+
+	// The first send outlives the deadline of the event
+	attempts := 0
+	send := func(event cloudevents.Event, destination *rdb.Service) cloudevents.Result {
+		attempts++
+		time.Sleep(30 * time.Millisecond)
+		return errors.New("connection reset")
+	}
+
+	result := deliver(event, table, table.Services[0], send)
+	if !errors.Is(result, ErrExpired) {
+		t.Fatalf("expected an expired result, got %v", result)
+	}
+	if ClassifyFailure(result) != FailureExpired {
+		t.Fatalf("expected failure class %s, got %s", FailureExpired, ClassifyFailure(result))
+	}
+	if attempts != 1 {
+		t.Fatalf("expected no retry after the deadline passed, got %d attempts", attempts)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		ratio    float64
+		reserve  int
+		deposits int
+		want     int // Retries the budget allows afterwards
+	}{
+		{ratio: 0.2, reserve: 10, deposits: 10, want: 2},
+		{ratio: 0.2, reserve: 10, deposits: 4, want: 0},
+		{ratio: 1, reserve: 10, deposits: 3, want: 3},
+		// The reserve caps what an idle period can save up
+		{ratio: 0.5, reserve: 1, deposits: 10, want: 1},
+		{ratio: 1, reserve: 5, deposits: 100, want: 5},
+		{ratio: 0, reserve: 10, deposits: 100, want: 0},
+	}
+
+	ratio, reserve := config.RetryBudgetRatio, config.RetryBudgetReserve
+	t.Cleanup(func() { config.RetryBudgetRatio, config.RetryBudgetReserve = ratio, reserve })
+
+	for _, test := range tests {
+		config.RetryBudgetRatio, config.RetryBudgetReserve = test.ratio, test.reserve
+		budget := &retryBudget{}
+		for i := 0; i < test.deposits; i++ {
+			budget.deposit()
+		}
+
+		got := 0
+		for budget.withdraw() {
+			got++
+		}
+		if got != test.want {
+			t.Errorf("ratio=%.1f reserve=%d after %d events: %d retries allowed, want %d",
+				test.ratio, test.reserve, test.deposits, got, test.want)
+		}
+	}
+}
+
+func TestDeliverFailsOverByWeight(t *testing.T) {
+	tests := []struct {
+		name        string
+		failing     map[string]int // Status code returned by failing services
+		maxAttempts int
+		budget      float64 // Retries left in the budget
+		wantTried   []string
+		wantSuccess bool
+	}{
+		{"first attempt succeeds", nil, 3, 10, []string{"a"}, true},
+		{"next best service takes over", map[string]int{"a": 503}, 3, 10, []string{"a", "b"}, true},
+		{"failover in weight order", map[string]int{"a": 503, "b": 429}, 3, 10, []string{"a", "b", "c"}, true},
+		{"attempts are limited", map[string]int{"a": 503, "b": 503}, 2, 10, []string{"a", "b"}, false},
+		{"every service fails", map[string]int{"a": 503, "b": 503, "c": 503}, 5, 10, []string{"a", "b", "c"}, false},
+		{"client errors are not retried", map[string]int{"a": 400}, 3, 10, []string{"a"}, false},
+		{"exhausted budget stops retries", map[string]int{"a": 503}, 3, 0, []string{"a"}, false},
+	}
+
+	maxAttempts, backoff, ratio := config.RetryMaxAttempts, config.RetryBackoff, config.RetryBudgetRatio
+	t.Cleanup(func() {
+		config.RetryMaxAttempts, config.RetryBackoff, config.RetryBudgetRatio = maxAttempts, backoff, ratio
+		retries.balance = float64(config.RetryBudgetReserve)
+	})
+	config.RetryBackoff = 0
+	config.RetryBudgetRatio = 0
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.RetryMaxAttempts = tt.maxAttempts
+			retries.balance = tt.budget
+
+			// Fresh names per case, so that breakers opened by earlier cases do not interfere
+			prefix := fmt.Sprintf("failover%d-", i)
+			table := newTestTable(map[string]float64{prefix + "a": 50, prefix + "c": 20, prefix + "b": 30})
+
+			var tried []string
+			send := func(event cloudevents.Event, destination *rdb.Service) cloudevents.Result {
+				name := strings.TrimPrefix(destination.Name, prefix)
+				tried = append(tried, name)
+				if status, ok := tt.failing[name]; ok {
+					return cloudevents.NewHTTPResult(status, http.StatusText(status))
+				}
+				return nil
+			}
+
+			result := deliver(newTestEvent(), table, table.Services[0], send)
+			if !reflect.DeepEqual(tried, tt.wantTried) {
+				t.Fatalf("tried %v, want %v", tried, tt.wantTried)
+			}
+			if success := result == nil; success != tt.wantSuccess {
+				t.Fatalf("delivered = %v (%v), want %v", success, result, tt.wantSuccess)
+			}
+
+			var deliveryErr *DeliveryError
+			if !tt.wantSuccess {
+				if !errors.As(result, &deliveryErr) {
+					t.Fatalf("expected a DeliveryError, got %T", result)
+				}
+				if want := prefix + tt.wantTried[len(tt.wantTried)-1]; deliveryErr.Destination != want || deliveryErr.Attempts != len(tt.wantTried) {
+					t.Errorf("failure at %s after %d attempts, want %s after %d", deliveryErr.Destination, deliveryErr.Attempts, want, len(tt.wantTried))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package routing
+
+import "os"
+
+// The package initializer loads the configuration, which refuses to start without the
+// connection settings. Package variables are initialized before init runs, so the tests
+// provide placeholders here unless the environment already sets them.
+var _ = setTestEnv()
+
+func setTestEnv() bool {
+	defaults := map[string]string{
+		"REDIS_URL":         "localhost:6379",
+		"REDIS_PASSWORD":    "test",
+		"RABBITMQ_URL":      "http://localhost:15672",
+		"RABBITMQ_USERNAME": "test",
+		"RABBITMQ_PASSWORD": "test",
+		"NUM_SERVICES":      "0",
+	}
+	for key, value := range defaults {
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+		}
+	}
+	return true
+}
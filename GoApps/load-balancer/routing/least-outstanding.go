@@ -0,0 +1,106 @@
+package routing
+
+import (
+	rdb "load-balancer/db"
+	"log"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// LeastOutstandingRoutingAlgorithm sends each event to the service with the fewest
+// in-flight requests relative to its CurrWeight, so a slow consumer stops receiving
+// its full share while its earlier events are still outstanding.
+type LeastOutstandingRoutingAlgorithm struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// Helper function to pick the service with the lowest (inFlight+1)/weight score.
+// The table is sorted by name so that ties are broken deterministically.
+func (l *LeastOutstandingRoutingAlgorithm) selectService(table *RoutingTable) *rdb.Service {
+	var destination *rdb.Service
+	bestScore := 0.0
+	for i, service := range table.Services {
+		weight := table.Weights[i]
+		if table.TotalWeight() == 0 {
+			// No weights published yet, treat every service equally
+			weight = 1
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		score := float64(l.inFlight[service.Name]+1) / weight
+		if destination == nil || score < bestScore {
+			destination = service
+			bestScore = score
+		}
+	}
+	return destination
+}
+
+func (l *LeastOutstandingRoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) cloudevents.Result {
+	table := NewRoutingTable(servicesMap)
+
+	l.mu.Lock()
+	if l.inFlight == nil {
+		l.inFlight = make(map[string]int)
+	}
+	destination := l.selectService(table)
+	if destination != nil {
+		l.inFlight[destination.Name]++
+		log.Printf("📊 Outstanding requests for %s: %d", destination.Name, l.inFlight[destination.Name])
+	}
+	l.mu.Unlock()
+
+	if destination == nil {
+		log.Println("❌ Error: Destination is empty. Least outstanding selection failed.")
+		return ErrNoDestination
+	}
+
+	send, release := l.trackSends(destination, sendEvent)
+	result := deliver(event, table, destination, send)
+	release()
+	return result
+}
+
+// Helper function to count every send as in flight while it runs. The reserved
+// destination was already counted at selection time, so that concurrent events see it;
+// when deliver skips it without sending, e.g. because its breaker opened after the
+// snapshot, the reservation is handed back before the next destination is counted, or
+// by the returned release function once delivery finished.
+func (l *LeastOutstandingRoutingAlgorithm) trackSends(reserved *rdb.Service, next sendFunc) (sendFunc, func()) {
+	// Hands the reservation back at most once, callers hold l.mu
+	releaseReservation := func() {
+		if reserved != nil {
+			l.inFlight[reserved.Name]--
+			reserved = nil
+		}
+	}
+
+	send := func(event cloudevents.Event, service *rdb.Service) cloudevents.Result {
+		l.mu.Lock()
+		if service == reserved {
+			reserved = nil
+		} else {
+			releaseReservation()
+			l.inFlight[service.Name]++
+		}
+		l.mu.Unlock()
+
+		defer func() {
+			l.mu.Lock()
+			l.inFlight[service.Name]--
+			l.mu.Unlock()
+		}()
+		return next(event, service)
+	}
+
+	release := func() {
+		l.mu.Lock()
+		releaseReservation()
+		l.mu.Unlock()
+	}
+	return send, release
+}
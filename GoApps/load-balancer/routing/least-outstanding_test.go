@@ -0,0 +1,41 @@
+package routing
+
+import (
+	"testing"
+
+	rdb "load-balancer/db"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+func TestLeastOutstandingReleasesSkippedReservation(t *testing.T) {
+	a := &rdb.Service{Name: "a"}
+	b := &rdb.Service{Name: "b"}
+	ack := func(cloudevents.Event, *rdb.Service) cloudevents.Result { return nil }
+
+	// a was reserved at selection time but skipped, the event went to b instead
+	l := &LeastOutstandingRoutingAlgorithm{inFlight: map[string]int{"a": 1}}
+	send, release := l.trackSends(a, ack)
+	send(cloudevents.NewEvent(), b)
+	release()
+	if l.inFlight["a"] != 0 || l.inFlight["b"] != 0 {
+		t.Fatalf("expected no outstanding requests after failover, got %v", l.inFlight)
+	}
+
+	// a was reserved and skipped, and no other destination was tried
+	l = &LeastOutstandingRoutingAlgorithm{inFlight: map[string]int{"a": 1}}
+	_, release = l.trackSends(a, ack)
+	release()
+	if l.inFlight["a"] != 0 {
+		t.Fatalf("expected no outstanding requests without any send, got %v", l.inFlight)
+	}
+
+	// a was reserved and sent to, its slot is released exactly once
+	l = &LeastOutstandingRoutingAlgorithm{inFlight: map[string]int{"a": 1}}
+	send, release = l.trackSends(a, ack)
+	send(cloudevents.NewEvent(), a)
+	release()
+	if l.inFlight["a"] != 0 {
+		t.Fatalf("expected no outstanding requests after delivery, got %v", l.inFlight)
+	}
+}
@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"load-balancer/config"
+	rdb "load-balancer/db"
+	"load-balancer/metrics"
+	"log"
+	"math/rand"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// Started once per process, SetAlgorithm creates a new P2C instance on every switch and
+// all of them share the cached queued_requests
+var qdReqsRefresher sync.Once
+
+// P2CRoutingAlgorithm samples two services weighted by CurrWeight and sends the event
+// to the one whose consumer reports fewer queued_requests.
+type P2CRoutingAlgorithm struct{}
+
+func (p *P2CRoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) cloudevents.Result {
+	// Start scraping queued_requests the first time the algorithm is used
+	qdReqsRefresher.Do(func() {
+		go metrics.StartQdReqsRefresher(config.QdReqsRefreshInterval)
+	})
+
+	table := NewRoutingTable(servicesMap)
+	first := table.Pick(rand.Float64())
+	if first == nil {
+		log.Println("❌ Error: Destination is empty. P2C selection failed.")
+		return ErrNoDestination
+	}
+
+	destination := first
+	if second := table.Without(first.Name).Pick(rand.Float64()); second != nil {
+		firstQueued, firstOk := metrics.CachedQdReqs(first.Name)
+		secondQueued, secondOk := metrics.CachedQdReqs(second.Name)
+		log.Printf("🎲 P2C candidates: %s (queued=%d) and %s (queued=%d)", first.Name, firstQueued, second.Name, secondQueued)
+
+		// Only compare queue depths when both consumers have been scraped
+		if firstOk && secondOk && secondQueued < firstQueued {
+			destination = second
+		}
+	}
+	log.Printf("🎯 Selected service: %s", destination.Name)
+
+	return deliver(event, table, destination, sendEvent)
+}
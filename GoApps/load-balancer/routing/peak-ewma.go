@@ -0,0 +1,113 @@
+package routing
+
+import (
+	"load-balancer/config"
+	rdb "load-balancer/db"
+	"load-balancer/metrics"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// PeakEWMARoutingAlgorithm tracks a peak-sensitive EWMA of the send round-trip time per
+// service and scales each CurrWeight by how fast that service currently answers
+// compared to the fastest one, steering traffic away from services whose latency rises.
+type PeakEWMARoutingAlgorithm struct {
+	mu        sync.Mutex
+	estimates map[string]*latencyEstimate
+}
+
+type latencyEstimate struct {
+	seconds float64
+	updated time.Time
+}
+
+// Helper function to fold a new round-trip time into the estimate. A sample above the
+// estimate replaces it immediately (peak), lower samples decay it with time constant
+// PEAK_EWMA_DECAY so that the estimate recovers gradually.
+func (p *PeakEWMARoutingAlgorithm) observe(service string, rtt time.Duration, now time.Time) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.estimates == nil {
+		p.estimates = make(map[string]*latencyEstimate)
+	}
+
+	sample := rtt.Seconds()
+	estimate, ok := p.estimates[service]
+	if !ok {
+		estimate = &latencyEstimate{seconds: sample, updated: now}
+		p.estimates[service] = estimate
+	} else if sample > estimate.seconds {
+		estimate.seconds = sample
+		estimate.updated = now
+	} else {
+		elapsed := now.Sub(estimate.updated).Seconds()
+		w := math.Exp(-elapsed / config.PeakEWMADecay.Seconds())
+		estimate.seconds = estimate.seconds*w + sample*(1-w)
+		estimate.updated = now
+	}
+	return estimate.seconds
+}
+
+// Helper function to compute latency factors for the table. Services without a
+// measurement yet get the best factor so that they are probed.
+func (p *PeakEWMARoutingAlgorithm) latencyFactors(table *RoutingTable) []float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fastest := math.Inf(1)
+	for _, service := range table.Services {
+		if estimate, ok := p.estimates[service.Name]; ok && estimate.seconds > 0 {
+			fastest = min(fastest, estimate.seconds)
+		}
+	}
+
+	factors := make([]float64, table.Len())
+	for i, service := range table.Services {
+		factors[i] = 1
+		if estimate, ok := p.estimates[service.Name]; ok && estimate.seconds > 0 && !math.IsInf(fastest, 1) {
+			factors[i] = fastest / estimate.seconds
+		}
+	}
+	return factors
+}
+
+func (p *PeakEWMARoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) cloudevents.Result {
+	table := NewRoutingTable(servicesMap)
+	factors := p.latencyFactors(table)
+	scaled := table.Scaled(factors)
+	log.Printf("⏱️ Latency factors: %v, Effective weights: %v", factors, scaled.Weights)
+
+	// Keep routing by CurrWeight alone if every service was scaled down to zero
+	if scaled.TotalWeight() == 0 {
+		scaled = table
+	}
+
+	destination := scaled.Pick(rand.Float64())
+	if destination == nil {
+		log.Println("❌ Error: Destination is empty. Peak EWMA selection failed.")
+		return ErrNoDestination
+	}
+	log.Printf("🎯 Selected service: %s", destination.Name)
+
+	return deliver(event, table, destination, p.send)
+}
+
+// Helper function to send an event while recording its round-trip time
+func (p *PeakEWMARoutingAlgorithm) send(event cloudevents.Event, destination *rdb.Service) cloudevents.Result {
+	start := time.Now()
+	result := sendEvent(event, destination)
+	end := time.Now()
+
+	// Failed sends are recorded too, a timeout is the strongest latency signal there is
+	estimate := p.observe(destination.Name, end.Sub(start), end)
+	metrics.UpdateLatencyEstimate(destination.Name, estimate)
+	log.Printf("⏱️ Round-trip time to %s: %v (ewma=%.3fs)", destination.Name, end.Sub(start), estimate)
+
+	return result
+}
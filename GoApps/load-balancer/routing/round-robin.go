@@ -1,12 +1,9 @@
 package routing
 
 import (
-	"context"
-	"fmt"
 	rdb "load-balancer/db"
 	"log"
 	"sync"
-	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 )
@@ -16,37 +13,17 @@ type RoundRobinRoutingAlgorithm struct {
 	mu      sync.Mutex
 }
 
-func (r *RoundRobinRoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	serviceNames := make([]string, 0, len(servicesMap))
-	for name := range servicesMap {
-		serviceNames = append(serviceNames, name)
+func (r *RoundRobinRoutingAlgorithm) RouteEvent(event cloudevents.Event, servicesMap map[string]*rdb.Service) cloudevents.Result {
+	table := NewRoutingTable(servicesMap)
+	if table.Len() == 0 {
+		log.Println("❌ Error: Destination is empty. Round-robin selection failed.")
+		return ErrNoDestination
 	}
 
-	destinationName := serviceNames[r.counter%len(serviceNames)]
+	r.mu.Lock()
+	destination := table.Services[r.counter%table.Len()]
 	r.counter++
+	r.mu.Unlock()
 
-	destination := servicesMap[destinationName]
-
-	destinationURL := fmt.Sprintf("http://%s.rabbitmq-setup.svc.cluster.local", destination.Name)
-	c, err := cloudevents.NewClientHTTP()
-	if err != nil {
-		log.Printf("❌ Failed to create client: %v", err)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	ctx = cloudevents.ContextWithTarget(ctx, destinationURL)
-	log.Printf("☁️ Sending CloudEvent to %s", destination.Name)
-
-	if result := c.Send(ctx, event); !cloudevents.IsACK(result) {
-		log.Printf("❌ Failed to send: %v", result)
-		return
-	}
-
-	log.Printf("✅ Successfully sent event to %s", destination.Name)
+	return deliver(event, table, destination, sendEvent)
 }
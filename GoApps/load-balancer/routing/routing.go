@@ -1,7 +1,10 @@
 package routing
 
 import (
+	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"time"
 
 	"load-balancer/config"
@@ -13,14 +16,27 @@ import (
 )
 
 var (
-	SelectedAlgorithm RoutingAlgorithm
+	// Registry of routing algorithm factories, keyed by ROUTING_ALGORITHM name
+	registry   = make(map[string]AlgorithmFactory)
+	registryMu sync.RWMutex
+
+	// Currently active algorithm, swapped at runtime through SetAlgorithm
+	selectedAlgorithm RoutingAlgorithm
+	selectedName      string
+	selectedMu        sync.RWMutex
 	//localRand         = rand.New(rand.NewSource(time.Now().UnixNano()))
 )
 
+// RoutingAlgorithm selects a destination for an event and dispatches it. RouteEvent
+// returns nil once a service ACKed the event, and the failed result otherwise so that
+// the receiver can decide whether the broker should redeliver it.
 type RoutingAlgorithm interface {
-	RouteEvent(event cloudevents.Event, servicesMap map[string]*db.Service)
+	RouteEvent(event cloudevents.Event, servicesMap map[string]*db.Service) cloudevents.Result
 }
 
+// AlgorithmFactory constructs a fresh instance of a routing algorithm
+type AlgorithmFactory func() (RoutingAlgorithm, error)
+
 func StartAdmissionRateUpdater(rdbClient *redis.Client) {
 	ticker := time.NewTicker(config.AdmissionRateInterval)
 	defer ticker.Stop()
@@ -30,15 +46,104 @@ func StartAdmissionRateUpdater(rdbClient *redis.Client) {
 	}
 }
 
+// Register adds a named algorithm factory to the registry, replacing any existing one
+func Register(name string, factory AlgorithmFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Algorithms returns the names of all registered algorithms in sorted order
+func Algorithms() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAlgorithm constructs a new instance of the named algorithm
+func NewAlgorithm(name string) (RoutingAlgorithm, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported routing algorithm: %s", name)
+	}
+	return factory()
+}
+
+// SelectedAlgorithm returns the active algorithm and its name
+func SelectedAlgorithm() (string, RoutingAlgorithm) {
+	selectedMu.RLock()
+	defer selectedMu.RUnlock()
+	return selectedName, selectedAlgorithm
+}
+
+// SetAlgorithm replaces the active algorithm with a fresh instance of the named one.
+// Events already being routed finish on the previous instance.
+func SetAlgorithm(name string) error {
+	algorithm, err := NewAlgorithm(name)
+	if err != nil {
+		return err
+	}
+
+	selectedMu.Lock()
+	previous := selectedName
+	selectedAlgorithm = algorithm
+	selectedName = name
+	selectedMu.Unlock()
+
+	log.Printf("🔀 Routing algorithm switched from %q to %q", previous, name)
+	return nil
+}
+
+// Helper function to register the algorithms shipped with the load balancer
+func registerBuiltinAlgorithms() {
+	Register("AIMD", func() (RoutingAlgorithm, error) {
+		return &AIMDRoutingAlgorithm{}, nil
+	})
+	Register("AIMDSmooth", func() (RoutingAlgorithm, error) {
+		return &AIMDRoutingAlgorithm{Selector: &SmoothWeightedSelector{}}, nil
+	})
+	Register("RoundRobin", func() (RoutingAlgorithm, error) {
+		return &RoundRobinRoutingAlgorithm{}, nil
+	})
+	Register("Random", func() (RoutingAlgorithm, error) {
+		return &RandomRoutingAlgorithm{}, nil
+	})
+	Register("LeastOutstanding", func() (RoutingAlgorithm, error) {
+		return &LeastOutstandingRoutingAlgorithm{}, nil
+	})
+	Register("P2C", func() (RoutingAlgorithm, error) {
+		return &P2CRoutingAlgorithm{}, nil
+	})
+	Register("PeakEWMA", func() (RoutingAlgorithm, error) {
+		return &PeakEWMARoutingAlgorithm{}, nil
+	})
+	Register("ConsistentHash", func() (RoutingAlgorithm, error) {
+		if config.FallbackAlgorithm == "ConsistentHash" {
+			return nil, fmt.Errorf("ConsistentHash cannot be its own fallback algorithm")
+		}
+		fallback, err := NewAlgorithm(config.FallbackAlgorithm)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ROUTING_FALLBACK_ALGORITHM: %v", err)
+		}
+		return &ConsistentHashRoutingAlgorithm{Fallback: fallback}, nil
+	})
+}
+
 func init() {
 	config.LoadConfig()
+	registerBuiltinAlgorithms()
+	retries.balance = float64(config.RetryBudgetReserve)
 
-	switch config.RoutingAlgorithm {
-	case "AIMD":
-		SelectedAlgorithm = &AIMDRoutingAlgorithm{}
-	case "RoundRobin":
-		SelectedAlgorithm = &RoundRobinRoutingAlgorithm{}
-	default:
-		log.Fatalf("❌ Invalid or unsupported ROUTING_ALGORITHM value: %s", config.RoutingAlgorithm)
+	if err := SetAlgorithm(config.RoutingAlgorithm); err != nil {
+		log.Fatalf("❌ Invalid or unsupported ROUTING_ALGORITHM value: %v", err)
 	}
 }
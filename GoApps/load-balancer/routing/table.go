@@ -0,0 +1,178 @@
+package routing
+
+import (
+	rdb "load-balancer/db"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RoutingTable is a stable snapshot of the services sorted by name together with the
+// weights read at snapshot time, so that every lookup during one routing decision sees
+// the same order and the same weights.
+type RoutingTable struct {
+	Services    []*rdb.Service
+	Weights     []float64
+	prefixSums  []float64
+	totalWeight float64
+}
+
+// NewRoutingTable snapshots the services map, leaving out services whose circuit breaker
+// is open. Negative weights are treated as zero.
+func NewRoutingTable(servicesMap map[string]*rdb.Service) *RoutingTable {
+	now := time.Now()
+	services := make([]*rdb.Service, 0, len(servicesMap))
+	for _, service := range servicesMap {
+		if !breakerFor(service.Name).Available(now) {
+			continue
+		}
+		services = append(services, service)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	table := &RoutingTable{
+		Services:   services,
+		Weights:    make([]float64, len(services)),
+		prefixSums: make([]float64, len(services)),
+	}
+	for i, service := range services {
+		table.Weights[i] = max(0, service.CurrWeight)
+		table.totalWeight += table.Weights[i]
+		table.prefixSums[i] = table.totalWeight
+	}
+	return table
+}
+
+// Scaled returns a new table whose weights are multiplied by the given per-service
+// factors, indexed like Services.
+func (t *RoutingTable) Scaled(factors []float64) *RoutingTable {
+	table := &RoutingTable{
+		Services:   t.Services,
+		Weights:    make([]float64, len(t.Services)),
+		prefixSums: make([]float64, len(t.Services)),
+	}
+	for i := range t.Services {
+		table.Weights[i] = max(0, t.Weights[i]*factors[i])
+		table.totalWeight += table.Weights[i]
+		table.prefixSums[i] = table.totalWeight
+	}
+	return table
+}
+
+// Without returns a new table that excludes the named service
+func (t *RoutingTable) Without(name string) *RoutingTable {
+	table := &RoutingTable{}
+	for i, service := range t.Services {
+		if service.Name == name {
+			continue
+		}
+		table.Services = append(table.Services, service)
+		table.Weights = append(table.Weights, t.Weights[i])
+		table.totalWeight += t.Weights[i]
+		table.prefixSums = append(table.prefixSums, table.totalWeight)
+	}
+	return table
+}
+
+func (t *RoutingTable) Len() int {
+	return len(t.Services)
+}
+
+func (t *RoutingTable) TotalWeight() float64 {
+	return t.totalWeight
+}
+
+// Pick maps a value in [0, 1) onto a service proportionally to its weight using a
+// binary search over the prefix sums. When every weight is zero the services are
+// treated as equally weighted.
+func (t *RoutingTable) Pick(value float64) *rdb.Service {
+	if len(t.Services) == 0 {
+		return nil
+	}
+	if t.totalWeight == 0 {
+		return t.Services[min(int(value*float64(len(t.Services))), len(t.Services)-1)]
+	}
+
+	target := value * t.totalWeight
+	index := sort.Search(len(t.prefixSums), func(i int) bool {
+		return t.prefixSums[i] > target
+	})
+	if index == len(t.prefixSums) {
+		index = len(t.prefixSums) - 1
+	}
+	return t.Services[index]
+}
+
+// WeightedSelector chooses a destination from a routing table according to its weights
+type WeightedSelector interface {
+	Select(table *RoutingTable) *rdb.Service
+}
+
+// RandomWeightedSelector picks each destination independently at random, so the
+// realised split converges to the weights over many events.
+type RandomWeightedSelector struct{}
+
+func (s *RandomWeightedSelector) Select(table *RoutingTable) *rdb.Service {
+	return table.Pick(rand.Float64())
+}
+
+// SmoothWeightedSelector implements smooth weighted round-robin as used by nginx: every
+// selection adds each weight to its service's running score, picks the highest score
+// and subtracts the total weight from it. The result is a deterministic interleaving
+// in which each service receives exactly its share over every cycle of the weights.
+type SmoothWeightedSelector struct {
+	mu      sync.Mutex
+	current map[string]float64
+}
+
+func (s *SmoothWeightedSelector) Select(table *RoutingTable) *rdb.Service {
+	if table.Len() == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		s.current = make(map[string]float64)
+	}
+
+	weights := table.Weights
+	totalWeight := table.TotalWeight()
+	if totalWeight == 0 {
+		// Fall back to plain round-robin while no weights are published
+		weights = make([]float64, table.Len())
+		for i := range weights {
+			weights[i] = 1
+		}
+		totalWeight = float64(table.Len())
+	}
+
+	// Forget scores of services that are no longer part of the table
+	if len(s.current) > table.Len() {
+		present := make(map[string]bool, table.Len())
+		for _, service := range table.Services {
+			present[service.Name] = true
+		}
+		for name := range s.current {
+			if !present[name] {
+				delete(s.current, name)
+			}
+		}
+	}
+
+	selected := -1
+	for i, service := range table.Services {
+		s.current[service.Name] += weights[i]
+		if selected < 0 || s.current[service.Name] > s.current[table.Services[selected].Name] {
+			selected = i
+		}
+	}
+
+	destination := table.Services[selected]
+	s.current[destination.Name] -= totalWeight
+	return destination
+}
@@ -0,0 +1,92 @@
+package routing
+
+import (
+	"math/rand"
+	"testing"
+
+	rdb "load-balancer/db"
+)
+
+// Helper function to build a routing table from name/weight pairs
+func newTestTable(weights map[string]float64) *RoutingTable {
+	servicesMap := make(map[string]*rdb.Service, len(weights))
+	for name, weight := range weights {
+		servicesMap[name] = &rdb.Service{Name: name, CurrWeight: weight}
+	}
+	return NewRoutingTable(servicesMap)
+}
+
+func TestPickFollowsWeights(t *testing.T) {
+	table := newTestTable(map[string]float64{"a": 10, "b": 30, "c": 60})
+	random := rand.New(rand.NewSource(1))
+
+	const draws = 100000
+	observed := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		observed[table.Pick(random.Float64()).Name]++
+	}
+
+	// Chi-square goodness of fit with 2 degrees of freedom, 13.82 is the critical value
+	// at a significance level of 0.001
+	chiSquare := 0.0
+	for i, service := range table.Services {
+		expected := draws * table.Weights[i] / table.TotalWeight()
+		diff := float64(observed[service.Name]) - expected
+		chiSquare += diff * diff / expected
+	}
+	if chiSquare > 13.82 {
+		t.Fatalf("observed split %v does not match the weights, chi-square=%.2f", observed, chiSquare)
+	}
+}
+
+func TestSmoothWeightedSelectorInterleavesExactly(t *testing.T) {
+	table := newTestTable(map[string]float64{"a": 5, "b": 1, "c": 1})
+	selector := &SmoothWeightedSelector{}
+
+	// The sequence nginx produces for weights 5, 1 and 1, repeated for two cycles
+	want := []string{"a", "a", "b", "a", "c", "a", "a"}
+	for cycle := 0; cycle < 2; cycle++ {
+		for i, name := range want {
+			if got := selector.Select(table).Name; got != name {
+				t.Fatalf("cycle %d, selection %d: got %s, want %s", cycle, i, got, name)
+			}
+		}
+	}
+}
+
+func TestZeroWeightsFallBackToEqualShares(t *testing.T) {
+	table := newTestTable(map[string]float64{"a": 0, "b": 0, "c": 0})
+
+	for value, want := range map[float64]string{0: "a", 0.4: "b", 0.7: "c", 0.999: "c"} {
+		if got := table.Pick(value).Name; got != want {
+			t.Errorf("Pick(%v) = %s, want %s", value, got, want)
+		}
+	}
+
+	selector := &SmoothWeightedSelector{}
+	counts := make(map[string]int)
+	for i := 0; i < 3*table.Len(); i++ {
+		counts[selector.Select(table).Name]++
+	}
+	for _, service := range table.Services {
+		if counts[service.Name] != 3 {
+			t.Errorf("round-robin fallback selected %s %d times, want 3", service.Name, counts[service.Name])
+		}
+	}
+}
+
+func TestSmoothWeightedSelectorDropsStaleScores(t *testing.T) {
+	selector := &SmoothWeightedSelector{}
+	selector.Select(newTestTable(map[string]float64{"a": 1, "b": 1, "c": 5}))
+	if _, ok := selector.current["c"]; !ok {
+		t.Fatal("expected a score for c after selecting from a table containing it")
+	}
+
+	selector.Select(newTestTable(map[string]float64{"a": 1, "b": 1}))
+	if _, ok := selector.current["c"]; ok {
+		t.Fatalf("score of c was kept after it left the table: %v", selector.current)
+	}
+	if len(selector.current) != 2 {
+		t.Fatalf("expected scores for a and b only, got %v", selector.current)
+	}
+}
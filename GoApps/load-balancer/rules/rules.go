@@ -0,0 +1,166 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"load-balancer/config"
+	"load-balancer/db"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+)
+
+// Match lists the CloudEvent attributes a rule requires. Empty fields match anything and
+// a value ending in "*" matches by prefix. Extensions are compared in their string form,
+// so "2" matches an integer extension of 2.
+type Match struct {
+	Type       string            `json:"type,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	Subject    string            `json:"subject,omitempty"`
+	Extensions map[string]string `json:"extensions,omitempty"`
+}
+
+// Rule restricts the events it matches to a subset of the services. The weighted
+// routing algorithm still decides between the services of that subset.
+type Rule struct {
+	Name     string   `json:"name"`
+	Match    Match    `json:"match"`
+	Services []string `json:"services"`
+}
+
+type ruleFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+var (
+	activeRules []Rule
+	rulesMu     sync.RWMutex
+)
+
+// Load reads, validates and activates the rules in the given JSON file. The previous
+// rules stay active when the file is invalid.
+func Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read rules file: %v", err)
+	}
+
+	var file ruleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse rules file: %v", err)
+	}
+	if err := validate(file.Rules); err != nil {
+		return err
+	}
+
+	rulesMu.Lock()
+	activeRules = file.Rules
+	rulesMu.Unlock()
+
+	log.Printf("📜 Loaded %d routing rules from %s", len(file.Rules), path)
+	return nil
+}
+
+// Reload reloads the rules from RULES_FILE
+func Reload() error {
+	if config.RulesFile == "" {
+		return fmt.Errorf("RULES_FILE is not set")
+	}
+	return Load(config.RulesFile)
+}
+
+// Rules returns the active rules
+func Rules() []Rule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return activeRules
+}
+
+// Helper function to validate rules against the configured services
+func validate(rules []Rule) error {
+	names := make(map[string]bool)
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		match := rule.Match
+		if match.Type == "" && match.Source == "" && match.Subject == "" && len(match.Extensions) == 0 {
+			return fmt.Errorf("rule %q does not match on any attribute", rule.Name)
+		}
+		for name := range match.Extensions {
+			if name != strings.ToLower(name) {
+				return fmt.Errorf("rule %q: extension names must be lower case, got %q", rule.Name, name)
+			}
+		}
+
+		if len(rule.Services) == 0 {
+			return fmt.Errorf("rule %q lists no services", rule.Name)
+		}
+		for _, service := range rule.Services {
+			if _, ok := db.ServicesMap[service]; !ok {
+				return fmt.Errorf("rule %q references unknown service %q", rule.Name, service)
+			}
+		}
+	}
+	return nil
+}
+
+// Helper function to compare an attribute against a rule pattern
+func matches(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
+
+func (r *Rule) matches(event cloudevents.Event) bool {
+	if !matches(r.Match.Type, event.Type()) ||
+		!matches(r.Match.Source, event.Source()) ||
+		!matches(r.Match.Subject, event.Subject()) {
+		return false
+	}
+	for name, pattern := range r.Match.Extensions {
+		value, ok := event.Extensions()[name]
+		if !ok {
+			return false
+		}
+		str, err := types.Format(value)
+		if err != nil || !matches(pattern, str) {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the services the event may be routed to: the services of the first
+// matching rule, or all services when no rule matches.
+func Select(event cloudevents.Event, servicesMap map[string]*db.Service) map[string]*db.Service {
+	for _, rule := range Rules() {
+		if !rule.matches(event) {
+			continue
+		}
+
+		subset := make(map[string]*db.Service, len(rule.Services))
+		for _, name := range rule.Services {
+			if service, ok := servicesMap[name]; ok {
+				subset[name] = service
+			}
+		}
+		log.Printf("📜 Event %s matched rule %q, routing among %v", event.ID(), rule.Name, rule.Services)
+		return subset
+	}
+	return servicesMap
+}
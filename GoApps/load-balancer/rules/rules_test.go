@@ -0,0 +1,147 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"load-balancer/db"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// Helper function to build an event with the attributes the rules match on
+func newTestEvent(eventType, source, subject string, extensions map[string]interface{}) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID("rules-test")
+	event.SetType(eventType)
+	event.SetSource(source)
+	event.SetSubject(subject)
+	for name, value := range extensions {
+		event.SetExtension(name, value)
+	}
+	return event
+}
+
+// Helper function to list the service names of a selection in order
+func namesOf(services map[string]*db.Service) []string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRuleMatching(t *testing.T) {
+	event := newTestEvent("order.created", "shop/eu", "order-42", map[string]interface{}{"tenant": "acme", "tier": 2})
+
+	tests := []struct {
+		name  string
+		match Match
+		want  bool
+	}{
+		{"exact type", Match{Type: "order.created"}, true},
+		{"other type", Match{Type: "order.deleted"}, false},
+		{"type prefix", Match{Type: "order.*"}, true},
+		{"prefix needs the whole prefix", Match{Type: "orders.*"}, false},
+		{"lone wildcard", Match{Source: "*"}, true},
+		{"all attributes", Match{Type: "order.created", Source: "shop/*", Subject: "order-42"}, true},
+		{"one attribute differs", Match{Type: "order.created", Source: "shop/us"}, false},
+		{"extension", Match{Extensions: map[string]string{"tenant": "acme"}}, true},
+		{"extension prefix", Match{Extensions: map[string]string{"tenant": "ac*"}}, true},
+		{"extension differs", Match{Extensions: map[string]string{"tenant": "globex"}}, false},
+		{"non-string extension", Match{Extensions: map[string]string{"tier": "2"}}, true},
+		{"missing extension", Match{Extensions: map[string]string{"region": "*"}}, false},
+	}
+
+	for _, tt := range tests {
+		rule := Rule{Name: tt.name, Match: tt.match, Services: []string{"service1"}}
+		if got := rule.matches(event); got != tt.want {
+			t.Errorf("%s: matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectUsesFirstMatchingRule(t *testing.T) {
+	servicesMap := map[string]*db.Service{
+		"service1": {Name: "service1"},
+		"service2": {Name: "service2"},
+		"service3": {Name: "service3"},
+	}
+
+	previous := Rules()
+	t.Cleanup(func() { activeRules = previous })
+	activeRules = []Rule{
+		{Name: "vip", Match: Match{Extensions: map[string]string{"tenant": "vip"}}, Services: []string{"service3"}},
+		{Name: "orders", Match: Match{Type: "order.*"}, Services: []string{"service1", "service2", "removed"}},
+	}
+
+	tests := []struct {
+		name  string
+		event cloudevents.Event
+		want  []string
+	}{
+		{"no rule matches", newTestEvent("payment.settled", "shop", "", nil), []string{"service1", "service2", "service3"}},
+		{"single rule matches", newTestEvent("order.created", "shop", "", nil), []string{"service1", "service2"}},
+		{"first rule wins", newTestEvent("order.created", "shop", "", map[string]interface{}{"tenant": "vip"}), []string{"service3"}},
+	}
+
+	for _, tt := range tests {
+		got := namesOf(Select(tt.event, servicesMap))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: selected %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: selected %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLoadRejectsInvalidRules(t *testing.T) {
+	services := db.ServicesMap
+	t.Cleanup(func() { db.ServicesMap = services })
+	db.ServicesMap = map[string]*db.Service{"service1": {Name: "service1"}}
+
+	previous := Rules()
+	t.Cleanup(func() { activeRules = previous })
+	valid := []Rule{{Name: "kept", Match: Match{Type: "a"}, Services: []string{"service1"}}}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"rules": [{"name": "r", "match": {"type": "order.*"}, "services": ["service1"]}]}`, false},
+		{"invalid JSON", `{"rules": [`, true},
+		{"missing name", `{"rules": [{"match": {"type": "a"}, "services": ["service1"]}]}`, true},
+		{"duplicate name", `{"rules": [{"name": "r", "match": {"type": "a"}, "services": ["service1"]}, {"name": "r", "match": {"type": "b"}, "services": ["service1"]}]}`, true},
+		{"matches everything", `{"rules": [{"name": "r", "match": {}, "services": ["service1"]}]}`, true},
+		{"upper case extension", `{"rules": [{"name": "r", "match": {"extensions": {"Tenant": "a"}}, "services": ["service1"]}]}`, true},
+		{"no services", `{"rules": [{"name": "r", "match": {"type": "a"}, "services": []}]}`, true},
+		{"unknown service", `{"rules": [{"name": "r", "match": {"type": "a"}, "services": ["service9"]}]}`, true},
+	}
+
+	for _, tt := range tests {
+		activeRules = valid
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		err := Load(path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Load error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		// A rejected file leaves the previous rules active
+		if tt.wantErr && (len(Rules()) != 1 || Rules()[0].Name != "kept") {
+			t.Errorf("%s: active rules changed to %v after a rejected file", tt.name, Rules())
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package weights
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"load-balancer/config"
+	"load-balancer/db"
+	"load-balancer/metrics"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Reasons a single service is considered congested
+const (
+	CongestionQueuedRequests = "queued_requests"
+	CongestionTooMany        = "429"
+	CongestionServer         = "5xx"
+	CongestionSaturation     = "saturation"
+)
+
+// SaturationChannelPrefix prefixes the Pub/Sub channels rate controllers report
+// saturation of their service on
+const SaturationChannelPrefix = "saturation:"
+
+type congestionSignal struct {
+	service string
+	reason  string
+}
+
+var (
+	congestionSignals = make(chan congestionSignal, 1000)
+
+	// Start of the current epoch of every service. Guarded by db.AdmissionRatesMutex.
+	lastEpoch = make(map[string]time.Time)
+)
+
+// SignalCongestion reports that a service is congested. It never blocks, so it can be
+// called while dispatching; signals arriving faster than they are handled are dropped.
+func SignalCongestion(service, reason string) {
+	select {
+	case congestionSignals <- congestionSignal{service: service, reason: reason}:
+	default:
+		log.Printf("⚠️ Congestion signal for %s (%s) dropped, too many pending signals", service, reason)
+	}
+}
+
+// StartCongestionMonitor starts a new epoch for every service reported as congested,
+// through SignalCongestion or by its rate controller on saturation:<service>
+func StartCongestionMonitor(rdb *redis.Client) {
+	go subscribeToSaturation(rdb)
+
+	for signal := range congestionSignals {
+		db.AdmissionRatesMutex.Lock()
+		if service, ok := db.ServicesMap[signal.service]; ok {
+			congest(rdb, service, signal.reason, time.Now())
+		} else {
+			log.Printf("CONGESTION SIGNAL FOR UNKNOWN SERVICE %s IGNORED", signal.service)
+		}
+		db.AdmissionRatesMutex.Unlock()
+	}
+}
+
+// Helper function to forward the saturation reports of the rate controllers
+func subscribeToSaturation(rdb *redis.Client) {
+	pubSub := rdb.PSubscribe(db.Ctx, SaturationChannelPrefix+"*")
+	defer pubSub.Close()
+	log.Printf("🔊 Subscribed to %s* channels", SaturationChannelPrefix)
+
+	for msg := range pubSub.Channel() {
+		service := strings.TrimPrefix(msg.Channel, SaturationChannelPrefix)
+		log.Printf("📡 Rate controller of %s reported saturation: %s", service, msg.Payload)
+		SignalCongestion(service, CongestionSaturation)
+	}
+}
+
+// Helper function to start a new epoch for a congested service, decreasing only its
+// admission rate. Signals within CONGESTION_COOLDOWN of the last epoch of the service
+// are ignored, the decrease they ask for already happened. Services whose control law
+// does not build on epochs are counted as unaffected. Callers hold
+// db.AdmissionRatesMutex.
+func congest(rdb *redis.Client, service *db.Service, reason string, currentTime time.Time) {
+	if last, ok := lastEpoch[service.Name]; ok && currentTime.Sub(last) < config.CongestionCooldown {
+		metrics.IncCongestionSignals(service.Name, reason, "ignored")
+		return
+	}
+
+	log.Printf("CONGESTION DETECTED FOR %s (%s), STARTING A NEW EPOCH", service.Name, reason)
+	startServiceEpoch(rdb, service, currentTime)
+
+	outcome := "decrease"
+	if !usesEpochs(controlLawFor(service)) {
+		outcome = "unaffected"
+	}
+	metrics.IncCongestionSignals(service.Name, reason, outcome)
+}
+
+// Helper function to report the services whose consumers queue more requests than
+// CONGESTION_QUEUED_REQUESTS
+func queuedRequestsCongested(queuedRequests map[string]int) map[string]bool {
+	congested := make(map[string]bool)
+	if config.CongestionQueuedRequests <= 0 {
+		return congested
+	}
+	for name, queued := range queuedRequests {
+		if queued > config.CongestionQueuedRequests {
+			congested[name] = true
+		}
+	}
+	return congested
+}
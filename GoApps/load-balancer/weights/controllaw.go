@@ -0,0 +1,185 @@
+package weights
+
+import (
+	"math"
+
+	"load-balancer/config"
+	"load-balancer/db"
+)
+
+// ControlInput is what a control law knows about a service when its admission rate is
+// updated
+type ControlInput struct {
+	Elapsed           float64 // Seconds since the current epoch of the service started
+	Interval          float64 // Seconds since the previous update
+	Replicas          int
+	QueuedRequests    int // queued_requests reported by the consumer
+	HasQueuedRequests bool
+	Latency           float64 // Mean dispatch latency since the previous update, in seconds
+	HasLatency        bool
+}
+
+// ControlLaw proposes the next raw admission rate of a service, which is then bounded by
+// the admission rate policy. Every service has a law of its own, so laws may keep state
+// between updates. Epochs only move the baseline EmptyQWeight, laws that do not build on
+// it react to their own congestion signals.
+type ControlLaw interface {
+	Rate(service *db.Service, input ControlInput) float64
+}
+
+// Control law of every service by name. Guarded by db.AdmissionRatesMutex.
+var controlLaws = make(map[string]ControlLaw)
+
+// InitializeControlLaws creates the control law selected by SERVICE<n>_CONTROL_LAW for
+// every service
+func InitializeControlLaws() {
+	db.AdmissionRatesMutex.Lock()
+	defer db.AdmissionRatesMutex.Unlock()
+
+	for i := 0; i < config.NumServices; i++ {
+		controlLaws[config.ServiceNames[i]] = NewControlLaw(i)
+	}
+}
+
+// NewControlLaw creates the control law configured for the service at serviceIndex
+func NewControlLaw(serviceIndex int) ControlLaw {
+	switch config.ControlLaws[serviceIndex] {
+	case "cubic":
+		return CubicLaw{C: config.CubicCs[serviceIndex]}
+	case "pid":
+		return &PIDLaw{
+			Setpoint: config.PIDSetpoints[serviceIndex],
+			Kp:       config.PIDKps[serviceIndex],
+			Ki:       config.PIDKis[serviceIndex],
+			Kd:       config.PIDKds[serviceIndex],
+		}
+	case "vegas":
+		return &VegasLaw{
+			Alpha: config.VegasAlphas[serviceIndex],
+			Beta:  config.VegasBetas[serviceIndex],
+			Step:  config.VegasSteps[serviceIndex],
+		}
+	default:
+		return AIMDLaw{}
+	}
+}
+
+// Helper function to get the control law of a service, AIMD unless configured otherwise
+func controlLawFor(service *db.Service) ControlLaw {
+	if law, ok := controlLaws[service.Name]; ok {
+		return law
+	}
+	return AIMDLaw{}
+}
+
+// Helper function to report whether a law builds on the baseline of the epoch, so that
+// starting an epoch decreases its rate
+func usesEpochs(law ControlLaw) bool {
+	switch law.(type) {
+	case AIMDLaw, CubicLaw:
+		return true
+	default:
+		return false
+	}
+}
+
+// Helper function to report whether any service is steered by its queued_requests
+func controlLawsNeedQueuedRequests() bool {
+	for _, law := range controlLaws {
+		if _, ok := law.(*PIDLaw); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AIMDLaw starts every epoch at Beta times the rate the previous one ended with and
+// increases it by Alpha per second and replica
+type AIMDLaw struct{}
+
+func (AIMDLaw) Rate(service *db.Service, input ControlInput) float64 {
+	return service.Beta*service.EmptyQWeight + float64(service.Alpha)*input.Elapsed*float64(input.Replicas)
+}
+
+// CubicLaw follows the CUBIC window curve C*(t-K)^3 + Wmax, where Wmax is the raw
+// admission rate the previous epoch ended with and K the time it takes to climb back to
+// Wmax from Beta*Wmax. The rate grows fast while far below Wmax, flattens out near it
+// and probes beyond it at an increasing pace. C is scaled by the number of replicas.
+type CubicLaw struct {
+	C float64
+}
+
+func (l CubicLaw) Rate(service *db.Service, input ControlInput) float64 {
+	wMax := service.EmptyQWeight
+	c := l.C * float64(input.Replicas)
+	k := math.Cbrt(wMax * (1 - service.Beta) / c)
+	return c*math.Pow(input.Elapsed-k, 3) + wMax
+}
+
+// PIDLaw steers the queued_requests of the consumer towards Setpoint. It works in
+// velocity form, changing the previous raw rate by the change of the PID output, so the
+// integral does not wind up while the rate is clamped. The rate is held while
+// queued_requests are unknown.
+type PIDLaw struct {
+	Setpoint float64
+	Kp       float64
+	Ki       float64
+	Kd       float64
+
+	started       bool
+	prevError     float64
+	prevPrevError float64
+}
+
+func (l *PIDLaw) Rate(service *db.Service, input ControlInput) float64 {
+	if !input.HasQueuedRequests || input.Interval <= 0 {
+		return service.RawAdmissionRate
+	}
+
+	e := l.Setpoint - float64(input.QueuedRequests)
+	if !l.started {
+		l.prevError, l.prevPrevError = e, e
+		l.started = true
+	}
+
+	delta := l.Kp*(e-l.prevError) +
+		l.Ki*e*input.Interval +
+		l.Kd*(e-2*l.prevError+l.prevPrevError)/input.Interval
+	l.prevPrevError, l.prevError = l.prevError, e
+
+	return service.RawAdmissionRate + delta
+}
+
+// VegasLaw estimates the events queued at the service from the dispatch latency, as the
+// admission rate times the latency above the lowest latency seen so far. Below Alpha
+// queued events the rate grows by Step per replica, above Beta it shrinks by Step per
+// replica and in between it holds. The rate is also held while nothing was dispatched to
+// the service.
+type VegasLaw struct {
+	Alpha float64
+	Beta  float64
+	Step  float64
+
+	baseLatency float64
+}
+
+func (l *VegasLaw) Rate(service *db.Service, input ControlInput) float64 {
+	rate := service.RawAdmissionRate
+	if !input.HasLatency {
+		return rate
+	}
+
+	if l.baseLatency == 0 || input.Latency < l.baseLatency {
+		l.baseLatency = input.Latency
+	}
+
+	queued := rate * (input.Latency - l.baseLatency)
+	switch {
+	case queued < l.Alpha:
+		return rate + l.Step*float64(input.Replicas)
+	case queued > l.Beta:
+		return rate - l.Step*float64(input.Replicas)
+	default:
+		return rate
+	}
+}
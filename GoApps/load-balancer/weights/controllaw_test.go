@@ -0,0 +1,78 @@
+package weights
+
+import (
+	"math"
+	"testing"
+
+	"load-balancer/db"
+)
+
+func TestAIMDLawGrowthCurve(t *testing.T) {
+	tests := []struct {
+		alpha    int
+		beta     float64
+		replicas int
+		elapsed  float64
+		want     float64
+	}{
+		// The epoch starts at Beta times the baseline
+		{alpha: 3, beta: 0.5, replicas: 1, elapsed: 0, want: 10},
+		// Growth is continuous, sub-second epochs already increase the rate
+		{alpha: 3, beta: 0.5, replicas: 1, elapsed: 0.25, want: 10.75},
+		{alpha: 3, beta: 0.5, replicas: 1, elapsed: 0.5, want: 11.5},
+		{alpha: 3, beta: 0.5, replicas: 1, elapsed: 1.5, want: 14.5},
+		// Alpha is added per replica
+		{alpha: 3, beta: 0.5, replicas: 2, elapsed: 0.5, want: 13},
+		{alpha: 5, beta: 0.5, replicas: 4, elapsed: 2, want: 50},
+		{alpha: 1, beta: 0.8, replicas: 3, elapsed: 0.1, want: 16.3},
+	}
+
+	for _, test := range tests {
+		service := &db.Service{Alpha: test.alpha, Beta: test.beta, EmptyQWeight: 20}
+		input := ControlInput{Elapsed: test.elapsed, Replicas: test.replicas}
+		if got := (AIMDLaw{}).Rate(service, input); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("alpha=%d beta=%.1f replicas=%d elapsed=%.2fs: rate %f, want %f",
+				test.alpha, test.beta, test.replicas, test.elapsed, got, test.want)
+		}
+	}
+}
+
+func TestCubicLawReturnsToWmax(t *testing.T) {
+	law := CubicLaw{C: 0.4}
+	service := &db.Service{Beta: 0.5, RawAdmissionRate: 80, EmptyQWeight: 80}
+
+	// The epoch starts at Beta*Wmax and climbs back to Wmax after K seconds
+	if got := law.Rate(service, ControlInput{Elapsed: 0, Replicas: 1}); math.Abs(got-40) > 1e-9 {
+		t.Fatalf("rate at the start of the epoch = %f, want 40", got)
+	}
+	k := math.Cbrt(80 * 0.5 / 0.4)
+	if got := law.Rate(service, ControlInput{Elapsed: k, Replicas: 1}); math.Abs(got-80) > 1e-9 {
+		t.Fatalf("rate after K=%f seconds = %f, want 80", k, got)
+	}
+}
+
+func TestVegasLawScalesBothDirectionsByReplicas(t *testing.T) {
+	tests := []struct {
+		latency  float64
+		replicas int
+		want     float64
+	}{
+		// 50 events/s and no latency above the base: nothing is queued, grow by Step per replica
+		{latency: 0.1, replicas: 1, want: 52},
+		{latency: 0.1, replicas: 3, want: 56},
+		// 0.06s above the base, 3 events queued: between Alpha and Beta, hold
+		{latency: 0.16, replicas: 3, want: 50},
+		// 0.2s above the base, 10 events queued: shrink by Step per replica
+		{latency: 0.3, replicas: 1, want: 48},
+		{latency: 0.3, replicas: 3, want: 44},
+	}
+
+	for _, test := range tests {
+		law := &VegasLaw{Alpha: 2, Beta: 4, Step: 2, baseLatency: 0.1}
+		service := &db.Service{RawAdmissionRate: 50}
+		input := ControlInput{Latency: test.latency, HasLatency: true, Replicas: test.replicas}
+		if got := law.Rate(service, input); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("latency=%.2fs replicas=%d: rate %f, want %f", test.latency, test.replicas, got, test.want)
+		}
+	}
+}
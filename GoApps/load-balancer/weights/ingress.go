@@ -0,0 +1,44 @@
+package weights
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"load-balancer/config"
+)
+
+// Exponentially weighted rate of arrivals: every arrival adds 1/tau and the estimate
+// decays by exp(-dt/tau) in between, so a steady stream of r events/s converges to r
+var (
+	ingressMu   sync.Mutex
+	ingressRate float64
+	lastArrival time.Time
+)
+
+// RecordArrival counts an event received by the load balancer towards the ingress rate
+func RecordArrival() {
+	ingressMu.Lock()
+	defer ingressMu.Unlock()
+
+	now := time.Now()
+	tau := config.IngressRateDecay.Seconds()
+	ingressRate = decayedRate(now) + 1/tau
+	lastArrival = now
+}
+
+// IngressRate returns the current estimate of the ingress rate in events per second
+func IngressRate() float64 {
+	ingressMu.Lock()
+	defer ingressMu.Unlock()
+	return decayedRate(time.Now())
+}
+
+// Helper function to decay the estimate up to now. Callers hold ingressMu.
+func decayedRate(now time.Time) float64 {
+	if lastArrival.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(lastArrival).Seconds()
+	return ingressRate * math.Exp(-elapsed/config.IngressRateDecay.Seconds())
+}
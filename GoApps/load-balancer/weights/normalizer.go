@@ -0,0 +1,121 @@
+package weights
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+
+	"load-balancer/metrics"
+)
+
+// Normalizer turns the admission rates of the services, keyed by service name, into
+// routing weights that sum to 100 with two decimals
+type Normalizer interface {
+	Normalize(rates map[string]float64) (map[string]float64, error)
+}
+
+// CPUSource reports the CPU usage and requested CPU of all replicas of a service, in cores
+type CPUSource interface {
+	CPUUsage(service string) float64
+	CPULimit(service string) float64
+}
+
+var errZeroWeight = errors.New("total weight is zero, cannot normalize")
+
+// NewNormalizer creates the normalizer selected by NORMALIZER
+func NewNormalizer(name string, minHeadroom float64) Normalizer {
+	switch name {
+	case "resource":
+		return &ResourceNormalizer{CPU: PrometheusCPUSource{}, MinHeadroom: minHeadroom}
+	default:
+		return SimpleNormalizer{}
+	}
+}
+
+// SimpleNormalizer scales the admission rates proportionally
+type SimpleNormalizer struct{}
+
+func (SimpleNormalizer) Normalize(rates map[string]float64) (map[string]float64, error) {
+	return roundToHundred(rates)
+}
+
+// ResourceNormalizer weights every admission rate by the CPU headroom of its service,
+// the unused fraction of its requested CPU, so busy services get a smaller share. The
+// headroom never drops below MinHeadroom to keep saturated services reachable, and an
+// idle service keeps its full rate. While any service reports no CPU request, as during
+// a cold start before its pods are scheduled, it falls back to simple normalization.
+type ResourceNormalizer struct {
+	CPU         CPUSource
+	MinHeadroom float64
+}
+
+func (n *ResourceNormalizer) Normalize(rates map[string]float64) (map[string]float64, error) {
+	adjusted := make(map[string]float64, len(rates))
+	for name, rate := range rates {
+		usage, limit := n.CPU.CPUUsage(name), n.CPU.CPULimit(name)
+		log.Printf("📈 CPU Usage for %s: %f of %f", name, usage, limit)
+		if limit == 0 {
+			log.Println("⚠️ Cold start detected. Performing simple normalization without resource utilization adjustment.")
+			return SimpleNormalizer{}.Normalize(rates)
+		}
+
+		headroom := math.Min(1, math.Max(n.MinHeadroom, 1-usage/limit))
+		adjusted[name] = rate * headroom
+	}
+	return roundToHundred(adjusted)
+}
+
+// PrometheusCPUSource reads the CPU figures of the services from Prometheus
+type PrometheusCPUSource struct{}
+
+func (PrometheusCPUSource) CPUUsage(service string) float64 {
+	return metrics.FetchCPUUsage(service)
+}
+
+func (PrometheusCPUSource) CPULimit(service string) float64 {
+	return metrics.FetchCPULimit(service)
+}
+
+// Helper function to scale weights to a total of 100 with two decimals. Every weight is
+// rounded down to a hundredth and the hundredths lost that way go to the weights with
+// the largest remainders, so the rounded weights add up to exactly 100.
+func roundToHundred(weights map[string]float64) (map[string]float64, error) {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		return nil, errZeroWeight
+	}
+
+	type remainder struct {
+		name     string
+		fraction float64
+	}
+	units := make(map[string]int, len(weights))
+	remainders := make([]remainder, 0, len(weights))
+	remaining := 10000 // Hundredths of 100
+	for name, weight := range weights {
+		exact := weight / total * 10000
+		units[name] = int(math.Floor(exact))
+		remaining -= units[name]
+		remainders = append(remainders, remainder{name: name, fraction: exact - math.Floor(exact)})
+	}
+
+	sort.Slice(remainders, func(i, j int) bool {
+		if remainders[i].fraction != remainders[j].fraction {
+			return remainders[i].fraction > remainders[j].fraction
+		}
+		return remainders[i].name < remainders[j].name
+	})
+	for i := 0; i < remaining; i++ {
+		units[remainders[i%len(remainders)].name]++
+	}
+
+	normalized := make(map[string]float64, len(weights))
+	for name, unit := range units {
+		normalized[name] = float64(unit) / 100
+	}
+	return normalized, nil
+}
@@ -0,0 +1,122 @@
+package weights
+
+import (
+	"math"
+	"testing"
+)
+
+// fakeCPU reports fixed CPU figures per service, in cores
+type fakeCPU struct {
+	usage map[string]float64
+	limit map[string]float64
+}
+
+func (f fakeCPU) CPUUsage(service string) float64 { return f.usage[service] }
+
+func (f fakeCPU) CPULimit(service string) float64 { return f.limit[service] }
+
+// Helper function to compare normalized weights up to floating point noise
+func assertWeights(t *testing.T, got, want map[string]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got weights %v, want %v", got, want)
+	}
+	for name, weight := range want {
+		if math.Abs(got[name]-weight) > 1e-9 {
+			t.Fatalf("got weights %v, want %v", got, want)
+		}
+	}
+}
+
+func TestResourceNormalizerColdStart(t *testing.T) {
+	rates := map[string]float64{"service1": 10, "service2": 30}
+	normalizer := &ResourceNormalizer{
+		// service2 has no pods yet, so no CPU request is reported for it
+		CPU: fakeCPU{
+			usage: map[string]float64{"service1": 0.9},
+			limit: map[string]float64{"service1": 1},
+		},
+		MinHeadroom: 0.05,
+	}
+
+	got, err := normalizer.Normalize(rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWeights(t, got, map[string]float64{"service1": 25, "service2": 75})
+}
+
+func TestResourceNormalizerZeroUtilization(t *testing.T) {
+	rates := map[string]float64{"service1": 10, "service2": 10}
+	normalizer := &ResourceNormalizer{
+		CPU: fakeCPU{
+			usage: map[string]float64{"service1": 0, "service2": 0.5},
+			limit: map[string]float64{"service1": 1, "service2": 1},
+		},
+		MinHeadroom: 0.05,
+	}
+
+	// The idle service keeps its whole rate, the other one half of it
+	got, err := normalizer.Normalize(rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWeights(t, got, map[string]float64{"service1": 66.67, "service2": 33.33})
+
+	// Without any load the shares follow the admission rates
+	normalizer.CPU = fakeCPU{
+		usage: map[string]float64{},
+		limit: map[string]float64{"service1": 1, "service2": 1},
+	}
+	got, err = normalizer.Normalize(map[string]float64{"service1": 10, "service2": 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWeights(t, got, map[string]float64{"service1": 25, "service2": 75})
+}
+
+func TestResourceNormalizerMinHeadroom(t *testing.T) {
+	rates := map[string]float64{"service1": 10, "service2": 10}
+	normalizer := &ResourceNormalizer{
+		CPU: fakeCPU{
+			usage: map[string]float64{"service1": 2, "service2": 0.5},
+			limit: map[string]float64{"service1": 1, "service2": 1},
+		},
+		MinHeadroom: 0.1,
+	}
+
+	// The saturated service stays reachable with a headroom of 0.1 against 0.5
+	got, err := normalizer.Normalize(rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWeights(t, got, map[string]float64{"service1": 16.67, "service2": 83.33})
+}
+
+func TestRoundToHundred(t *testing.T) {
+	got, err := roundToHundred(map[string]float64{"service1": 1, "service2": 1, "service3": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hundredth lost by rounding goes to the first name among equal remainders
+	assertWeights(t, got, map[string]float64{"service1": 33.34, "service2": 33.33, "service3": 33.33})
+
+	got, err = roundToHundred(map[string]float64{"service1": 1, "service2": 2, "service3": 4, "service4": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0.0
+	for _, weight := range got {
+		if math.Abs(weight*100-math.Round(weight*100)) > 1e-9 {
+			t.Errorf("weight %v has more than two decimals", weight)
+		}
+		total += weight
+	}
+	if math.Abs(total-100) > 1e-9 {
+		t.Fatalf("weights %v add up to %v, want 100", got, total)
+	}
+
+	if _, err := roundToHundred(map[string]float64{"service1": 0}); err != errZeroWeight {
+		t.Fatalf("got error %v for zero weights, want %v", err, errZeroWeight)
+	}
+}
@@ -0,0 +1,46 @@
+package weights
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"load-balancer/config"
+	"load-balancer/db"
+	"load-balancer/metrics"
+)
+
+// Helper function to bound the admission rate proposed by AIMD for a service. The change
+// from the previous raw rate is limited to ADMISSION_RATE_MAX_STEP in both directions, so
+// a multiplicative decrease is spread over several updates too. The result is then
+// clamped to the service's floor and ceiling. Every bound that applied is counted.
+func applyAdmissionPolicy(service *db.Service, proposed float64) float64 {
+	rate := proposed
+
+	if step := config.AdmissionRateMaxStep; step > 0 && service.RawAdmissionRate > 0 {
+		previous := service.RawAdmissionRate
+		if bounded := math.Max(previous-step, math.Min(previous+step, rate)); bounded != rate {
+			log.Printf("ADMISSION RATE FOR %s CHANGED TOO FAST (%f -> %f), LIMITED TO: %f", service.Name, previous, rate, bounded)
+			metrics.IncAdmissionRateLimitHits(service.Name, "slew")
+			rate = bounded
+		}
+	}
+
+	if rate > service.MaxAdmissionRate {
+		log.Printf("ADMISSION RATE FOR %s EXCEEDED MAX LIMIT, SET TO: %f", service.Name, service.MaxAdmissionRate)
+		metrics.IncAdmissionRateLimitHits(service.Name, "max")
+		rate = service.MaxAdmissionRate
+	} else if rate < service.MinAdmissionRate {
+		log.Printf("ADMISSION RATE FOR %s FELL BELOW MIN LIMIT, SET TO: %f", service.Name, service.MinAdmissionRate)
+		metrics.IncAdmissionRateLimitHits(service.Name, "min")
+		rate = service.MinAdmissionRate
+	}
+
+	return rate
+}
+
+// Helper function to report whether the current AIMD epoch ran longer than EPOCH_TIMEOUT
+// without the queue emptying
+func epochTimedOut(elapsed time.Duration) bool {
+	return config.EpochTimeout > 0 && elapsed >= config.EpochTimeout
+}
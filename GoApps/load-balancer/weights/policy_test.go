@@ -0,0 +1,38 @@
+package weights
+
+import (
+	"testing"
+
+	"load-balancer/config"
+	"load-balancer/db"
+)
+
+func TestApplyAdmissionPolicyLimitsBothDirections(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxStep  float64
+		proposed float64
+		want     float64
+	}{
+		{"increase within step", 5, 53, 53},
+		{"increase beyond step", 5, 70, 55},
+		{"decrease within step", 5, 47, 47},
+		{"decrease beyond step", 5, 25, 45},
+		{"unlimited decrease", 0, 25, 25},
+		{"step does not lift the floor", 50, 0.5, 1},
+		{"step does not lift the ceiling", 100, 150, 100},
+	}
+
+	maxStep := config.AdmissionRateMaxStep
+	t.Cleanup(func() { config.AdmissionRateMaxStep = maxStep })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AdmissionRateMaxStep = tt.maxStep
+			service := &db.Service{Name: "service1", RawAdmissionRate: 50, MinAdmissionRate: 1, MaxAdmissionRate: 100}
+			if got := applyAdmissionPolicy(service, tt.proposed); got != tt.want {
+				t.Errorf("applyAdmissionPolicy(%v) = %v, want %v", tt.proposed, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,14 @@
 package weights
 
 import (
-	"fmt"
+	"encoding/json"
 	"log"
-	"math"
 	"time"
 
 	"load-balancer/config"
 	"load-balancer/db"
 	"load-balancer/metrics"
+	"load-balancer/queue"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -16,6 +16,7 @@ import (
 var (
 	maxAdmissionRate int
 	minAdmissionRate int
+	normalizer       Normalizer = SimpleNormalizer{}
 )
 
 func InitializeWeights() {
@@ -27,11 +28,15 @@ func InitializeWeights() {
 	maxAdmissionRate = config.MaxAdmissionRate
 
 	log.Printf("📋 Admission Rate Config: min=%d, max=%d", minAdmissionRate, maxAdmissionRate)
+
+	// Select how admission rates are turned into routing weights
+	normalizer = NewNormalizer(config.Normalizer, config.NormalizerMinHeadroom)
+	log.Printf("📋 Using %s weight normalization", config.Normalizer)
 }
 
 func InitializeTkIfNotExists(rdb *redis.Client) error {
 	// Always initialize tk to the current time minus 0.1 seconds
-	tk := time.Now().Add(-100 * time.Millisecond).Unix() // Initialize 'tk' to the current timestamp minus 0.1 seconds
+	tk := time.Now().Add(-100 * time.Millisecond).UnixMilli() // Initialize 'tk' to the current timestamp minus 0.1 seconds
 	err := rdb.Set(db.Ctx, db.TkKey, tk, 0).Err()
 	if err != nil {
 		log.Printf("ERROR INITIALIZING TK IN REDIS: %v", err)
@@ -47,39 +52,70 @@ func UpdateAdmissionRates(rdb *redis.Client, currentTime time.Time) {
 
 	log.Println("STARTING ADMISSION RATE UPDATE")
 
-	tk, err := rdb.Get(db.Ctx, db.TkKey).Int64()
+	// Services that have not started an epoch of their own yet share the global tk
+	globalTk, err := rdb.Get(db.Ctx, db.TkKey).Int64()
 	if err != nil {
 		log.Printf("ERROR RETRIEVING TK VALUE FROM REDIS: %v", err)
 		return
 	}
-	log.Printf("RETRIEVED TK VALUE: %d", tk)
+	log.Printf("RETRIEVED TK VALUE: %d", globalTk)
+
+	interval := currentTime.Sub(db.LastUpdateTime).Seconds()
+	db.LastUpdateTime = currentTime
 
-	elapsedTime := currentTime.Sub(time.Unix(tk, 0)).Seconds()
-	log.Printf("ELAPSED TIME SINCE TK: %f SECONDS", elapsedTime)
+	var queuedRequests map[string]int
+	if config.CongestionQueuedRequests > 0 || controlLawsNeedQueuedRequests() {
+		queuedRequests = metrics.FetchQdReqs()
+	}
+	congested := queuedRequestsCongested(queuedRequests)
 
 	for _, service := range db.ServicesMap {
 		log.Printf("UPDATING ADMISSION RATE FOR SERVICE: %s", service.Name)
 		replicas := metrics.FetchReplicaNum(service.Name)
 		replicas = max(1, replicas)
 
-		// Apply AIMD on the admission rate with `EmptyQWeight` as the baseline
-		admissionRate := service.Beta*float64(service.EmptyQWeight) + float64(service.Alpha*int(elapsedTime)*replicas)
+		if congested[service.Name] {
+			congest(rdb, service, CongestionQueuedRequests, currentTime)
+		}
+
+		tk := serviceTk(rdb, service, globalTk)
+		elapsedTime := currentTime.Sub(tkTime(tk)).Seconds()
+		log.Printf("ELAPSED TIME SINCE TK OF %s: %f SECONDS", service.Name, elapsedTime)
+
+		// Force a multiplicative decrease when the service has gone too long without a new
+		// epoch. The mutex is already held, so the epoch is restarted directly. Laws that
+		// do not work in epochs have nothing to time out.
+		law := controlLawFor(service)
+		if usesEpochs(law) && epochTimedOut(time.Duration(elapsedTime*float64(time.Second))) {
+			log.Printf("EPOCH OF %s TIMED OUT AFTER %f SECONDS, FORCING MULTIPLICATIVE DECREASE", service.Name, elapsedTime)
+			startServiceEpoch(rdb, service, currentTime)
+			metrics.IncAdmissionRateLimitHits(service.Name, "epoch_timeout")
+			elapsedTime = 0
+		}
+
+		// Apply the control law of the service, `EmptyQWeight` is the baseline of the epoch
+		input := ControlInput{
+			Elapsed:  elapsedTime,
+			Interval: interval,
+			Replicas: replicas,
+		}
+		input.QueuedRequests, input.HasQueuedRequests = queuedRequests[service.Name]
+		input.Latency, input.HasLatency = metrics.TakeDispatchLatency(service.Name)
+		admissionRate := law.Rate(service, input)
 
 		log.Printf("CALCULATED ADMISSION RATE FOR %s: %f", service.Name, admissionRate)
 
-		// // Ensure the admission rate is within the logical bounds
-		// if admissionRate > maxAdmissionRate {
-		// 	admissionRate = maxAdmissionRate
-		// 	log.Printf("ADMISSION RATE FOR %s EXCEEDED MAX LIMIT, SET TO: %d", service.Name, maxAdmissionRate)
-		// } else if admissionRate < minAdmissionRate {
-		// 	admissionRate = minAdmissionRate
-		// 	log.Printf("ADMISSION RATE FOR %s FELL BELOW MIN LIMIT, SET TO: %d", service.Name, minAdmissionRate)
-		// }
+		// Ensure the admission rate is within the bounds of the service
+		admissionRate = applyAdmissionPolicy(service, admissionRate)
 
+		service.RawAdmissionRate = admissionRate
 		service.CurrWeight = admissionRate
 
 		// Save the updated admission rate in Redis for the respective service
-		err := rdb.HSet(db.Ctx, db.ServiceKeyPrefix+service.Name, "curr_weight", service.CurrWeight).Err()
+		err := rdb.HSet(db.Ctx, db.ServiceKeyPrefix+service.Name, map[string]interface{}{
+			"raw_admission_rate": service.RawAdmissionRate,
+			"curr_weight":        service.CurrWeight,
+		}).Err()
 		if err != nil {
 			log.Printf("ERROR UPDATING ADMISSION RATE FOR SERVICE %s IN REDIS: %v", service.Name, err)
 		} else {
@@ -88,7 +124,7 @@ func UpdateAdmissionRates(rdb *redis.Client, currentTime time.Time) {
 	}
 
 	// Normalize the admission rates for routing, considering resource utilization
-	performSimpleNormalization(rdb)
+	normalizeWeights(rdb)
 
 	// Publish the normalized admission rates for admission controllers
 	publishAdmissionRates(rdb)
@@ -96,127 +132,25 @@ func UpdateAdmissionRates(rdb *redis.Client, currentTime time.Time) {
 	log.Println("COMPLETED ADMISSION RATE UPDATE")
 }
 
-// func normalizeWeights(rdb *redis.Client) {
-// 	log.Println("⚖️ STARTING WEIGHT NORMALIZATION")
-
-// 	totalWeight := 0.0
-// 	totalResourceUtilization := 0.0
-// 	anyColdStart := false // Flag to detect if any service is in cold start
-
-// 	// Calculate total weight and total resource utilization for normalization
-// 	for _, service := range db.ServicesMap {
-// 		totalWeight += service.CurrWeight
-// 		cpuUsage := metrics.FetchCPUUsage(service.Name)
-
-// 		// If any service has zero CPU usage, we mark it as a cold start
-// 		if cpuUsage == 0 {
-// 			anyColdStart = true
-// 		}
-
-// 		totalResourceUtilization += cpuUsage // We only use CPU utilization for this case
-// 		log.Printf("📈 CPU Usage for %s: %f", service.Name, cpuUsage)
-// 	}
-
-// 	// Check if we should perform simple normalization (due to cold start)
-// 	if anyColdStart {
-// 		log.Println("⚠️ Cold start detected. Performing simple normalization without resource utilization adjustment.")
-// 		performSimpleNormalization(rdb, totalWeight)
-// 		return
-// 	}
-
-// 	// Perform resource-based normalization if there is no cold start
-// 	if totalWeight == 0 {
-// 		log.Println("⚠️ ERROR: TOTAL WEIGHT IS ZERO, CANNOT NORMALIZE")
-// 		return
-// 	}
-
-// 	if totalResourceUtilization == 0 {
-// 		log.Println("⚠️ ERROR: TOTAL RESOURCE UTILIZATION IS ZERO, CANNOT NORMALIZE WITH RESOURCES")
-// 		return
-// 	}
-
-// 	normalizationFactor := 100.0 / float64(totalWeight)
-// 	weightedNormalizationFactor := totalResourceUtilization / float64(totalWeight)
-
-// 	roundedWeights := make(map[string]float64) // Store rounded values as float64
-// 	totalRoundedWeight := 0.0
-
-// 	for _, service := range db.ServicesMap {
-// 		// Normalize weights considering resource utilization
-// 		normalizedWeight := (float64(service.CurrWeight) * normalizationFactor) * weightedNormalizationFactor
-// 		roundedWeight := math.Round(normalizedWeight*100) / 100 // Round to 2 decimal places
-// 		roundedWeights[service.Name] = roundedWeight
-// 		totalRoundedWeight += roundedWeight
-// 		log.Printf("🔄 NORMALIZED WEIGHT FOR %s CONSIDERING RESOURCES: %.2f", service.Name, roundedWeight)
-// 	}
-
-// 	roundingError := 100.0 - totalRoundedWeight
-
-// 	for _, service := range db.ServicesMap {
-// 		if roundingError == 0 {
-// 			break
-// 		}
-// 		if roundedWeights[service.Name] > 0 && roundingError > 0.01 {
-// 			roundedWeights[service.Name] += 0.01
-// 			roundingError -= 0.01
-// 		}
-// 	}
-
-// 	for _, service := range db.ServicesMap {
-// 		// Convert back to integer or floating-point representation based on the weight usage in Redis
-// 		service.CurrWeight = roundedWeights[service.Name]
-
-// 		err := rdb.HSet(db.Ctx, db.ServiceKeyPrefix+service.Name, "curr_weight", service.CurrWeight).Err()
-// 		if err != nil {
-// 			log.Printf("❌ ERROR UPDATING NORMALIZED WEIGHT FOR SERVICE %s IN REDIS: %v", service.Name, err)
-// 		} else {
-// 			log.Printf("✅ UPDATED NORMALIZED WEIGHT FOR %s: %d", service.Name, service.CurrWeight)
-// 		}
-// 	}
-
-// 	log.Println("✔️ COMPLETED WEIGHT NORMALIZATION")
-// }
-
-func performSimpleNormalization(rdb *redis.Client) {
-	log.Println("⚖️ STARTING SIMPLE NORMALIZATION")
-
-	totalWeight := 0.0
-	for _, service := range db.ServicesMap {
-		totalWeight += service.CurrWeight
-	}
-
-	if totalWeight == 0 {
-		log.Println("⚠️ ERROR: TOTAL WEIGHT IS ZERO, CANNOT NORMALIZE")
-		return
-	}
-
-	// Normalization factor to make weights sum up to 100
-	normalizationFactor := 100.0 / totalWeight
-	roundedWeights := make(map[string]float64)
-	totalRoundedWeight := 0.0
+// Helper function to replace the admission rates by routing weights summing to 100,
+// using the normalizer selected by NORMALIZER
+func normalizeWeights(rdb *redis.Client) {
+	log.Println("⚖️ STARTING WEIGHT NORMALIZATION")
 
+	rates := make(map[string]float64, len(db.ServicesMap))
 	for _, service := range db.ServicesMap {
-		normalizedWeight := service.CurrWeight * normalizationFactor
-		roundedWeight := math.Round(normalizedWeight*100) / 100 // Round to 2 decimal places
-		roundedWeights[service.Name] = roundedWeight
-		totalRoundedWeight += roundedWeight
-		log.Printf("🔄 NORMALIZED WEIGHT FOR %s (Simple): %.2f", service.Name, roundedWeight)
+		rates[service.Name] = service.CurrWeight
 	}
 
-	// Adjust any rounding errors to ensure total weight equals 100
-	roundingError := 100.0 - totalRoundedWeight
-	for _, service := range db.ServicesMap {
-		if roundingError == 0 {
-			break
-		}
-		if roundedWeights[service.Name] > 0 && roundingError > 0.01 {
-			roundedWeights[service.Name] += 0.01
-			roundingError -= 0.01
-		}
+	normalized, err := normalizer.Normalize(rates)
+	if err != nil {
+		log.Printf("⚠️ ERROR: %v", err)
+		return
 	}
 
 	for _, service := range db.ServicesMap {
-		service.CurrWeight = roundedWeights[service.Name]
+		service.CurrWeight = normalized[service.Name]
+		log.Printf("🔄 NORMALIZED WEIGHT FOR %s: %.2f", service.Name, service.CurrWeight)
 
 		err := rdb.HSet(db.Ctx, db.ServiceKeyPrefix+service.Name, "curr_weight", service.CurrWeight).Err()
 		if err != nil {
@@ -226,11 +160,21 @@ func performSimpleNormalization(rdb *redis.Client) {
 		}
 	}
 
-	log.Println("✔️ COMPLETED SIMPLE WEIGHT NORMALIZATION")
+	log.Println("✔️ COMPLETED WEIGHT NORMALIZATION")
+}
+
+// Helper function to convert a stored tk into a time. tk is stored in Unix milliseconds,
+// values below 1e12 (before September 2001 in milliseconds) were written in Unix seconds
+// by earlier versions and are read as such.
+func tkTime(tk int64) time.Time {
+	if tk < 1e12 {
+		return time.Unix(tk, 0)
+	}
+	return time.UnixMilli(tk)
 }
 
 func updateTkInRedis(rdb *redis.Client, currentTime time.Time) {
-	tk := currentTime.Unix()
+	tk := currentTime.UnixMilli()
 	err := rdb.Set(db.Ctx, db.TkKey, tk, 0).Err()
 	if err != nil {
 		log.Printf("ERROR UPDATING TK VALUE IN REDIS: %v", err)
@@ -242,24 +186,11 @@ func updateTkInRedis(rdb *redis.Client, currentTime time.Time) {
 func createEmptyQueueEvent(rdb *redis.Client, currentTime time.Time) {
 	if !db.PrevQueueEmpty {
 		log.Println("STARTING EMPTY QUEUE EVENT ROUTINE")
-		updateTkInRedis(rdb, currentTime)
 
 		db.AdmissionRatesMutex.Lock()
 		defer db.AdmissionRatesMutex.Unlock()
 
-		for _, service := range db.ServicesMap {
-			log.Printf("UPDATING EMPTY QUEUE WEIGHT FOR SERVICE: %s", service.Name)
-			service.EmptyQWeight = service.CurrWeight // Set the EmptyQWeight to the current admission rate
-
-			err := rdb.HSet(db.Ctx, db.ServiceKeyPrefix+service.Name, "emptyq_weight", service.EmptyQWeight).Err()
-			if err != nil {
-				log.Printf("ERROR UPDATING EMPTYQ WEIGHT FOR SERVICE %s IN REDIS: %v", service.Name, err)
-			}
-
-			// Update the Prometheus metric
-			db.EmptyQWeights[service.Name] = float64(service.EmptyQWeight)
-			metrics.UpdateGamma()
-		}
+		startEpoch(rdb, currentTime)
 
 		db.PrevQueueEmpty = true
 		log.Println("COMPLETED EMPTY QUEUE EVENT ROUTINE")
@@ -268,26 +199,119 @@ func createEmptyQueueEvent(rdb *redis.Client, currentTime time.Time) {
 	}
 }
 
+// Helper function to start a new AIMD epoch for all services at currentTime, taking the
+// current admission rates as the baselines decreased by Beta. Callers hold
+// db.AdmissionRatesMutex.
+func startEpoch(rdb *redis.Client, currentTime time.Time) {
+	updateTkInRedis(rdb, currentTime)
+
+	for _, service := range db.ServicesMap {
+		startServiceEpoch(rdb, service, currentTime)
+	}
+}
+
+// Helper function to start a new AIMD epoch for a single service, storing its tk in the
+// hash of the service. The baseline is the raw admission rate, the unit every control
+// law works in, not the normalized routing weight. Callers hold db.AdmissionRatesMutex.
+func startServiceEpoch(rdb *redis.Client, service *db.Service, currentTime time.Time) {
+	log.Printf("UPDATING EMPTY QUEUE WEIGHT FOR SERVICE: %s", service.Name)
+	service.EmptyQWeight = service.RawAdmissionRate // Set the EmptyQWeight to the current raw admission rate
+	lastEpoch[service.Name] = currentTime
+
+	err := rdb.HSet(db.Ctx, db.ServiceKeyPrefix+service.Name, map[string]interface{}{
+		"emptyq_weight": service.EmptyQWeight,
+		db.TkKey:        currentTime.UnixMilli(),
+	}).Err()
+	if err != nil {
+		log.Printf("ERROR UPDATING EMPTYQ WEIGHT FOR SERVICE %s IN REDIS: %v", service.Name, err)
+	}
+
+	// Update the Prometheus metric without holding up the admission rate updates, gamma
+	// scrapes the consumer of the service
+	db.EmptyQWeights[service.Name] = float64(service.EmptyQWeight)
+	go metrics.UpdateGamma(service.Name, service.EmptyQWeight, service.Beta, service.Alpha)
+}
+
+// Helper function to read the tk of a service from its hash, falling back to the
+// global tk until the service started an epoch of its own
+func serviceTk(rdb *redis.Client, service *db.Service, globalTk int64) int64 {
+	tk, err := rdb.HGet(db.Ctx, db.ServiceKeyPrefix+service.Name, db.TkKey).Int64()
+	if err == redis.Nil {
+		return globalTk
+	}
+	if err != nil {
+		log.Printf("ERROR RETRIEVING TK VALUE OF %s FROM REDIS: %v", service.Name, err)
+		return globalTk
+	}
+	return tk
+}
+
 func UpdateEmptyQWeightRoutine() {
 	rdb := db.NewRedisClient()
 	currentTime := time.Now()
 	createEmptyQueueEvent(rdb, currentTime)
 }
 
+// ConsumeQueueTransitions starts an empty queue event whenever the trigger queue drains
+// and re-arms it once messages arrive again, until the transitions channel is closed
+func ConsumeQueueTransitions(transitions <-chan queue.Transition) {
+	for transition := range transitions {
+		if transition.Empty {
+			UpdateEmptyQWeightRoutine()
+		} else {
+			db.PrevQueueEmpty = false
+		}
+	}
+}
+
+// AdmissionRateMessage is published on admission_rate:<service>. Rate is the absolute
+// admission rate of the service, its share of the total throughput, in Unit.
+type AdmissionRateMessage struct {
+	Rate         float64 `json:"rate"`
+	Unit         string  `json:"unit"`
+	SharePercent float64 `json:"share_percent"`
+	Basis        string  `json:"basis"` // Where the total throughput came from
+}
+
+// Helper function to get the total throughput shared out between the services. Until
+// any ingress was measured MAX_ADMISSION_RATE stands in for it.
+func totalThroughput() (float64, string) {
+	measured := IngressRate()
+	metrics.UpdateIngressRate(measured)
+
+	if config.TotalThroughput > 0 {
+		return config.TotalThroughput, "configured"
+	}
+	if measured > 0 {
+		return measured, "measured"
+	}
+	return float64(config.MaxAdmissionRate), "max_admission_rate"
+}
+
 func publishAdmissionRates(rdb *redis.Client) {
-	log.Println("📢 PUBLISHING NORMALIZED ADMISSION RATES TO REDIS")
-	for _, service := range db.ServicesMap {
-		admissionRate := float64(service.CurrWeight)
+	log.Println("📢 PUBLISHING ADMISSION RATES TO REDIS")
+	total, basis := totalThroughput()
+	log.Printf("📈 TOTAL THROUGHPUT: %.2f events/s (%s)", total, basis)
 
-		// Convert admissionRate to a string before publishing
-		admissionRateStr := fmt.Sprintf("%f", admissionRate)
+	for _, service := range db.ServicesMap {
+		message := AdmissionRateMessage{
+			Rate:         service.CurrWeight / 100 * total,
+			Unit:         "events/s",
+			SharePercent: service.CurrWeight,
+			Basis:        basis,
+		}
+		payload, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("❌ ERROR ENCODING ADMISSION RATE FOR SERVICE %s: %v", service.Name, err)
+			continue
+		}
 		channel := "admission_rate:" + service.Name
 
-		err := rdb.Publish(db.Ctx, channel, admissionRateStr).Err() // Publish the string
+		err = rdb.Publish(db.Ctx, channel, payload).Err()
 		if err != nil {
 			log.Printf("❌ ERROR PUBLISHING ADMISSION RATE FOR SERVICE %s: %v", service.Name, err)
 		} else {
-			log.Printf("✅ PUBLISHED ADMISSION RATE FOR %s: %s", service.Name, admissionRateStr)
+			log.Printf("✅ PUBLISHED ADMISSION RATE FOR %s: %s", service.Name, payload)
 		}
 	}
 	log.Println("📤 ALL ADMISSION RATES PUBLISHED SUCCESSFULLY!")
@@ -0,0 +1,107 @@
+package weights
+
+import (
+	"testing"
+	"time"
+
+	"load-balancer/config"
+	"load-balancer/db"
+	"load-balancer/queue"
+)
+
+func TestTkTimeReadsMilliseconds(t *testing.T) {
+	want := time.Date(2024, 6, 1, 12, 0, 0, 250*int(time.Millisecond), time.UTC)
+	if got := tkTime(want.UnixMilli()); !got.Equal(want) {
+		t.Fatalf("tkTime(%d) = %v, want %v", want.UnixMilli(), got, want)
+	}
+}
+
+func TestTkTimeReadsLegacySeconds(t *testing.T) {
+	// Values written by earlier versions in Unix seconds are still read correctly
+	want := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if got := tkTime(want.Unix()); !got.Equal(want) {
+		t.Fatalf("tkTime(%d) = %v, want %v", want.Unix(), got, want)
+	}
+
+	// Elapsed time since a legacy tk is measured in seconds, not in milliseconds
+	if elapsed := want.Add(1500 * time.Millisecond).Sub(tkTime(want.Unix())); elapsed != 1500*time.Millisecond {
+		t.Fatalf("elapsed time since legacy tk = %v, want 1.5s", elapsed)
+	}
+}
+
+// Helper monitor signalling every sample, so that the test knows when Watch saw a state
+type sampledMonitor struct {
+	*queue.FakeMonitor
+	sampled chan struct{}
+}
+
+func (m *sampledMonitor) State() (queue.State, error) {
+	state, err := m.FakeMonitor.State()
+	select {
+	case m.sampled <- struct{}{}:
+	default:
+	}
+	return state, err
+}
+
+func TestQueueTransitionsStartEpochs(t *testing.T) {
+	tests := []struct {
+		name      string
+		states    []queue.State
+		wantEpoch bool
+		wantEmpty bool
+	}{
+		{"busy queue", []queue.State{{Messages: 5}}, false, false},
+		{"queue drains", []queue.State{{Messages: 5}, {Messages: 0}}, true, true},
+		{"queue empty at start", []queue.State{{Messages: 0}}, true, true},
+		{"queue drains and refills", []queue.State{{Messages: 5}, {Messages: 0}, {Messages: 3}}, true, false},
+		{"publishing outpaces delivery", []queue.State{{Messages: 5}, {Messages: 0, PublishRate: 20, DeliverRate: 10}}, false, false},
+	}
+
+	// Nothing listens on the Redis address, the epoch is only recorded in memory
+	redisURL := config.RedisURL
+	t.Cleanup(func() { config.RedisURL = redisURL })
+	config.RedisURL = "127.0.0.1:1"
+
+	// The services map stays in place, gamma updates started by an epoch still read it
+	service := &db.Service{Name: "service1", RawAdmissionRate: 42}
+	db.ServicesMap = map[string]*db.Service{service.Name: service}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.EmptyQWeight = 0
+			db.PrevQueueEmpty = false
+			delete(lastEpoch, service.Name)
+
+			monitor := &sampledMonitor{FakeMonitor: queue.NewFakeMonitor(), sampled: make(chan struct{})}
+			done := make(chan bool)
+			consumed := make(chan struct{})
+			go func() {
+				defer close(consumed)
+				ConsumeQueueTransitions(queue.Watch(monitor, time.Millisecond, done))
+			}()
+
+			for _, state := range tt.states {
+				monitor.SetState(state)
+				// The third sample started after the state was set, so the transition of
+				// the sample before it was emitted
+				for i := 0; i < 3; i++ {
+					<-monitor.sampled
+				}
+			}
+			close(done)
+			<-consumed
+
+			_, started := lastEpoch[service.Name]
+			if started != tt.wantEpoch {
+				t.Fatalf("epoch started = %v, want %v", started, tt.wantEpoch)
+			}
+			if db.PrevQueueEmpty != tt.wantEmpty {
+				t.Errorf("queue empty = %v, want %v", db.PrevQueueEmpty, tt.wantEmpty)
+			}
+			if tt.wantEpoch && service.EmptyQWeight != service.RawAdmissionRate {
+				t.Errorf("EmptyQWeight = %v, want the raw admission rate %v", service.EmptyQWeight, service.RawAdmissionRate)
+			}
+		})
+	}
+}
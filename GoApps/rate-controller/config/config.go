@@ -15,6 +15,33 @@ var (
 	Beta           float64
 	ServiceURL     string // URL for the consuming service
 
+	// Priority classes, a share of the admission rate is reserved for high priority
+	PriorityExtension   string
+	DefaultPriority     string
+	HighPriorityReserve float64
+
+	// Saturation reports sent to the load balancer on saturation:<ThisService>
+	SaturationWaitThreshold  time.Duration
+	SaturationReportInterval time.Duration
+
+	// Event deadlines, given as an absolute time or as a TTL relative to the time attribute
+	DeadlineExtension string
+	TTLExtension      string
+
+	// Transport settings of the pooled forwarding client
+	ForwardTimeout             time.Duration
+	ForwardIdleConnTimeout     time.Duration
+	ForwardMaxIdleConnsPerHost int
+	ForwardDisableKeepAlives   bool
+	ForwardH2C                 bool
+
+	// Dead-letter sink receiving events that could not be forwarded
+	DeadLetterSink     string
+	DeadLetterFile     string
+	DeadLetterAMQPURL  string
+	DeadLetterExchange string
+	DeadLetterURL      string
+
 	RedisURL  string
 	RedisPass string
 )
@@ -59,6 +86,115 @@ func LoadConfig() {
 		}
 	}
 
+	PriorityExtension = os.Getenv("PRIORITY_EXTENSION")
+	if PriorityExtension == "" {
+		PriorityExtension = "priority"
+	}
+
+	// Events without a valid priority extension get the same class as in the load balancer
+	DefaultPriority = os.Getenv("DEFAULT_PRIORITY")
+	switch DefaultPriority {
+	case "high", "normal", "low":
+	case "":
+		DefaultPriority = "normal"
+	default:
+		log.Fatalf("❌ Invalid DEFAULT_PRIORITY value: %s", DefaultPriority)
+	}
+
+	HighPriorityReserve = 0.2
+	if reserveStr := os.Getenv("HIGH_PRIORITY_RESERVE"); reserveStr != "" {
+		HighPriorityReserve, err = strconv.ParseFloat(reserveStr, 64)
+		if err != nil || HighPriorityReserve < 0 || HighPriorityReserve >= 1 {
+			log.Fatalf("❌ Invalid HIGH_PRIORITY_RESERVE value: %s", reserveStr)
+		}
+	}
+
+	SaturationWaitThreshold = time.Second
+	if thresholdStr := os.Getenv("SATURATION_WAIT_THRESHOLD"); thresholdStr != "" {
+		threshold, err := strconv.Atoi(thresholdStr)
+		if err != nil || threshold <= 0 {
+			log.Fatalf("❌ Invalid SATURATION_WAIT_THRESHOLD value: %s", thresholdStr)
+		}
+		SaturationWaitThreshold = time.Duration(threshold) * time.Millisecond
+	}
+
+	SaturationReportInterval = time.Second
+	if intervalStr := os.Getenv("SATURATION_REPORT_INTERVAL"); intervalStr != "" {
+		interval, err := strconv.Atoi(intervalStr)
+		if err != nil || interval < 0 {
+			log.Fatalf("❌ Invalid SATURATION_REPORT_INTERVAL value: %s", intervalStr)
+		}
+		SaturationReportInterval = time.Duration(interval) * time.Millisecond
+	}
+
+	DeadlineExtension = os.Getenv("DEADLINE_EXTENSION")
+	if DeadlineExtension == "" {
+		DeadlineExtension = "deadline"
+	}
+
+	TTLExtension = os.Getenv("TTL_EXTENSION")
+	if TTLExtension == "" {
+		TTLExtension = "ttl"
+	}
+
+	ForwardTimeout = 10 * time.Second
+	if timeoutStr := os.Getenv("FORWARD_TIMEOUT"); timeoutStr != "" {
+		timeout, err := strconv.Atoi(timeoutStr)
+		if err != nil || timeout <= 0 {
+			log.Fatalf("❌ Invalid FORWARD_TIMEOUT value: %s", timeoutStr)
+		}
+		ForwardTimeout = time.Duration(timeout) * time.Millisecond
+	}
+
+	ForwardIdleConnTimeout = 90 * time.Second
+	if idleTimeoutStr := os.Getenv("FORWARD_IDLE_CONN_TIMEOUT"); idleTimeoutStr != "" {
+		idleTimeout, err := strconv.Atoi(idleTimeoutStr)
+		if err != nil || idleTimeout <= 0 {
+			log.Fatalf("❌ Invalid FORWARD_IDLE_CONN_TIMEOUT value: %s", idleTimeoutStr)
+		}
+		ForwardIdleConnTimeout = time.Duration(idleTimeout) * time.Millisecond
+	}
+
+	ForwardMaxIdleConnsPerHost = 100
+	if maxIdleStr := os.Getenv("FORWARD_MAX_IDLE_CONNS_PER_HOST"); maxIdleStr != "" {
+		ForwardMaxIdleConnsPerHost, err = strconv.Atoi(maxIdleStr)
+		if err != nil || ForwardMaxIdleConnsPerHost < 1 {
+			log.Fatalf("❌ Invalid FORWARD_MAX_IDLE_CONNS_PER_HOST value: %s", maxIdleStr)
+		}
+	}
+
+	ForwardDisableKeepAlives, _ = strconv.ParseBool(os.Getenv("FORWARD_DISABLE_KEEP_ALIVES"))
+	ForwardH2C, _ = strconv.ParseBool(os.Getenv("FORWARD_H2C"))
+
+	DeadLetterSink = os.Getenv("DEADLETTER_SINK")
+	switch DeadLetterSink {
+	case "":
+		DeadLetterSink = "none"
+	case "none", "file", "amqp", "http":
+	default:
+		log.Fatalf("❌ Invalid DEADLETTER_SINK value: %s", DeadLetterSink)
+	}
+
+	DeadLetterFile = os.Getenv("DEADLETTER_FILE")
+	if DeadLetterFile == "" {
+		DeadLetterFile = "deadletter.ndjson"
+	}
+
+	DeadLetterAMQPURL = os.Getenv("DEADLETTER_AMQP_URL")
+	if DeadLetterSink == "amqp" && DeadLetterAMQPURL == "" {
+		log.Fatal("❌ DEADLETTER_AMQP_URL environment variable is not set")
+	}
+
+	DeadLetterExchange = os.Getenv("DEADLETTER_EXCHANGE")
+	if DeadLetterExchange == "" {
+		DeadLetterExchange = "deadletter"
+	}
+
+	DeadLetterURL = os.Getenv("DEADLETTER_URL")
+	if DeadLetterSink == "http" && DeadLetterURL == "" {
+		log.Fatal("❌ DEADLETTER_URL environment variable is not set")
+	}
+
 	RedisURL = os.Getenv("REDIS_URL")
 	if RedisURL == "" {
 		log.Fatal("❌ REDIS_URL environment variable is not set")
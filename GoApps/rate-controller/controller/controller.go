@@ -1,16 +1,29 @@
 package controller
 
 import (
+	"context"
 	"log"
 	"sync"
 
+	"rate-controller/config"
+
 	"golang.org/x/time/rate"
 )
 
+// Priority classes carried in the PRIORITY_EXTENSION extension of an event
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
 type RateController struct {
 	mu            sync.Mutex
 	admissionRate float64
 	Limiter       *rate.Limiter
+	// Limits events other than high priority to the unreserved share of the admission
+	// rate, so HIGH_PRIORITY_RESERVE of Limiter always remains for high priority
+	BulkLimiter *rate.Limiter
 }
 
 // NewRateController initializes a new RateController with the given alpha and beta values
@@ -19,7 +32,24 @@ func NewRateController() *RateController {
 	return &RateController{
 		admissionRate: initialRate,
 		Limiter:       rate.NewLimiter(rate.Limit(initialRate), 1), // Create a rate limiter
+		BulkLimiter:   rate.NewLimiter(rate.Limit(bulkRate(initialRate)), 1),
+	}
+}
+
+// Helper function to compute the rate left to normal and low priority events
+func bulkRate(admissionRate float64) float64 {
+	return admissionRate * (1 - config.HighPriorityReserve)
+}
+
+// Wait blocks until an event of the given priority may be forwarded. High priority
+// events only wait for the admission rate, all others also for the bulk share of it.
+func (rc *RateController) Wait(ctx context.Context, priority string) error {
+	if priority != PriorityHigh {
+		if err := rc.BulkLimiter.Wait(ctx); err != nil {
+			return err
+		}
 	}
+	return rc.Limiter.Wait(ctx)
 }
 
 // UpdateAdmissionRateFromRedis updates the admission rate and rate limiter based on the value received from Redis
@@ -30,8 +60,9 @@ func (rc *RateController) UpdateAdmissionRateFromRedis(newAdmissionRate float64)
 	// Update the internal admission rate
 	rc.admissionRate = newAdmissionRate
 
-	// Update the rate limiter to use the new admission rate
+	// Update the rate limiters to use the new admission rate
 	rc.Limiter.SetLimit(rate.Limit(newAdmissionRate))
+	rc.BulkLimiter.SetLimit(rate.Limit(bulkRate(newAdmissionRate)))
 
-	log.Printf("🔄 Updated rate limiter to admission rate: %f", newAdmissionRate)
+	log.Printf("🔄 Updated rate limiter to admission rate: %f (%f for bulk priorities)", newAdmissionRate, bulkRate(newAdmissionRate))
 }
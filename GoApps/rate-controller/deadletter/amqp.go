@@ -0,0 +1,83 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// AMQPSink publishes records as persistent messages to a durable fanout exchange, with
+// the stage as routing key. The connection is re-established on the next write after
+// it breaks.
+type AMQPSink struct {
+	mu       sync.Mutex
+	url      string
+	exchange string
+	conn     *amqp.Connection
+	ch       *amqp.Channel
+}
+
+func NewAMQPSink(url, exchange string) (*AMQPSink, error) {
+	s := &AMQPSink{url: url, exchange: exchange}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Helper function to dial RabbitMQ and declare the exchange. Callers hold s.mu.
+func (s *AMQPSink) connect() error {
+	conn, err := amqp.Dial(s.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open a channel: %w", err)
+	}
+	if err := ch.ExchangeDeclare(s.exchange, amqp.ExchangeFanout, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to declare exchange %s: %w", s.exchange, err)
+	}
+
+	s.conn = conn
+	s.ch = ch
+	log.Printf("✅ Connected dead-letter sink to exchange %s", s.exchange)
+	return nil
+}
+
+func (s *AMQPSink) Write(record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil || s.conn.IsClosed() {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	return s.ch.Publish(s.exchange, record.Stage, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    record.Event.ID(),
+		Timestamp:    record.FailedAt,
+		Body:         body,
+	})
+}
+
+func (s *AMQPSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,56 @@
+package deadline
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"rate-controller/config"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+)
+
+// Of computes the deadline of an event from its DEADLINE_EXTENSION extension (an RFC 3339
+// timestamp) or its TTL_EXTENSION extension (seconds or a Go duration, counted from the
+// time attribute). When both are set the earlier one wins. Events without a valid
+// deadline never expire.
+func Of(event cloudevents.Event) (time.Time, bool) {
+	var deadline time.Time
+	extensions := event.Extensions()
+
+	if value, ok := extensions[config.DeadlineExtension]; ok {
+		if t, err := types.ToTime(value); err == nil {
+			deadline = t
+		} else {
+			log.Printf("⚠️ Ignoring invalid %s extension on event %s: %v", config.DeadlineExtension, event.ID(), err)
+		}
+	}
+
+	if value, ok := extensions[config.TTLExtension]; ok && !event.Time().IsZero() {
+		if ttl, err := parseTTL(value); err == nil {
+			if expiry := event.Time().Add(ttl); deadline.IsZero() || expiry.Before(deadline) {
+				deadline = expiry
+			}
+		} else {
+			log.Printf("⚠️ Ignoring invalid %s extension on event %s: %v", config.TTLExtension, event.ID(), err)
+		}
+	}
+
+	return deadline, !deadline.IsZero()
+}
+
+// Helper function to parse a TTL given as whole seconds or as a Go duration string
+func parseTTL(value interface{}) (time.Duration, error) {
+	if seconds, err := types.ToInteger(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	s, err := types.ToString(value)
+	if err != nil {
+		return 0, err
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(s)
+}
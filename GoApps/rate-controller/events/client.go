@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"rate-controller/config"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"golang.org/x/net/http2"
+)
+
+// newForwardClient creates the CloudEvents client shared by every forwarded event. Its
+// transport keeps connections to the consuming service alive, or multiplexes events over
+// cleartext HTTP/2 when FORWARD_H2C is set.
+func newForwardClient() (cloudevents.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	var transport http.RoundTripper
+	if config.ForwardH2C {
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			ReadIdleTimeout: config.ForwardIdleConnTimeout,
+		}
+	} else {
+		transport = &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         dialer.DialContext,
+			MaxIdleConnsPerHost: config.ForwardMaxIdleConnsPerHost,
+			IdleConnTimeout:     config.ForwardIdleConnTimeout,
+			DisableKeepAlives:   config.ForwardDisableKeepAlives,
+		}
+	}
+
+	return cloudevents.NewClientHTTP(
+		cehttp.WithTarget(config.ServiceURL),
+		cehttp.WithRoundTripper(transport),
+	)
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rate-controller/config"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// BenchmarkForward compares creating a client for every event, as forwarding did before
+// the client was shared, against the shared forwarding client
+func BenchmarkForward(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	config.ServiceURL = server.URL
+	config.ForwardIdleConnTimeout = 90 * time.Second
+	config.ForwardMaxIdleConnsPerHost = 100
+
+	event := cloudevents.NewEvent()
+	event.SetID("benchmark")
+	event.SetSource("rate-controller/benchmark")
+	event.SetType("benchmark")
+	event.SetData(cloudevents.ApplicationJSON, map[string]string{"payload": "benchmark"})
+
+	send := func(b *testing.B, c cloudevents.Client) {
+		if result := c.Send(context.Background(), event); !cloudevents.IsACK(result) {
+			b.Fatalf("send failed: %v", result)
+		}
+	}
+
+	b.Run("PerCallClient", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c, err := cloudevents.NewClientHTTP(cloudevents.WithTarget(config.ServiceURL))
+				if err != nil {
+					b.Fatal(err)
+				}
+				send(b, c)
+			}
+		})
+	})
+
+	b.Run("SharedClient", func(b *testing.B) {
+		c, err := newForwardClient()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				send(b, c)
+			}
+		})
+	})
+}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"log"
+	"time"
+
+	"rate-controller/config"
+	"rate-controller/deadletter"
+	"rate-controller/metrics"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// Helper function to discard an expired event. It is dead-lettered and ACKed, so the
+// load balancer does not retry it on another service.
+func expire(event cloudevents.Event, priority, stage string, received time.Time) cloudevents.Result {
+	log.Printf("⌛ Event %s expired, discarding it at %s", event.ID(), stage)
+	metrics.IncEventsExpired(stage)
+	metrics.IncEventsDropped(priority, "expired")
+	deadletter.Write(deadletter.Record{
+		Event:       event,
+		Stage:       "rate-controller",
+		Destination: config.ServiceName,
+		LastError:   "event deadline exceeded at " + stage,
+		ReceivedAt:  received,
+		FailedAt:    time.Now(),
+	})
+	return cloudevents.ResultACK
+}
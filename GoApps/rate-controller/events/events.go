@@ -2,21 +2,29 @@ package events
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"rate-controller/config"
 	"rate-controller/controller"
+	"rate-controller/deadletter"
+	"rate-controller/deadline"
 	"rate-controller/metrics"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
 	"github.com/go-redis/redis/v8"
 )
 
 var (
 	rateController *controller.RateController
 	rdbClient      *redis.Client
+	forwardClient  cloudevents.Client
 	//httpClient     = &http.Client{}
 )
 
@@ -41,7 +49,7 @@ func SubscribeToAdmissionRate(rdb *redis.Client) {
 		admissionRateStr := msg.Payload
 
 		// Attempt to parse the admission rate
-		admissionRate, err := strconv.ParseFloat(admissionRateStr, 64)
+		admissionRate, err := parseAdmissionRate(admissionRateStr)
 		if err != nil {
 			log.Printf("⚠️ Error parsing admission rate: %v", err)
 			continue
@@ -58,44 +66,125 @@ func SubscribeToAdmissionRate(rdb *redis.Client) {
 	}
 }
 
+// Helper function to parse an admission rate update. The load balancer publishes JSON
+// with an explicit unit, older versions published a bare number.
+func parseAdmissionRate(payload string) (float64, error) {
+	var message struct {
+		Rate *float64 `json:"rate"`
+		Unit string   `json:"unit"`
+	}
+	if err := json.Unmarshal([]byte(payload), &message); err == nil && message.Rate != nil {
+		if message.Unit != "" && message.Unit != "events/s" {
+			return 0, fmt.Errorf("unsupported admission rate unit %q", message.Unit)
+		}
+		return *message.Rate, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(payload), 64)
+}
+
 // HandleEvent processes incoming CloudEvents and forwards them to the consuming service with rate-limiting applied.
 func HandleEvent(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+	received := time.Now()
+	priority := priorityOf(event)
+
+	// Wait for the rate limiter at most until the deadline of the event
+	waitCtx := ctx
+	expiry, hasDeadline := deadline.Of(event)
+	if hasDeadline {
+		if !received.Before(expiry) {
+			return expire(event, priority, "receive", received)
+		}
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithDeadline(ctx, expiry)
+		defer cancel()
+	}
+
 	// Wait until the rate limiter allows us to process the event
-	err := rateController.Limiter.Wait(ctx)
+	err := rateController.Wait(waitCtx, priority)
+	if err != nil && hasDeadline && ctx.Err() == nil {
+		// The request is still alive, so it was the event deadline that cut the wait short
+		reportSaturation("expired")
+		return expire(event, priority, "admission", received)
+	}
 	if err != nil {
-		log.Printf("❌ Error applying rate limit: %v", err)
+		log.Printf("❌ Error applying rate limit to %s priority event: %v", priority, err)
+		metrics.IncEventsDropped(priority, "rate_limited")
+		reportSaturation("rate_limited")
 		return cloudevents.NewHTTPResult(http.StatusTooManyRequests, "Rate limit exceeded")
 	}
+	if waited := time.Since(received); waited > config.SaturationWaitThreshold {
+		log.Printf("🐢 %s priority event waited %v for the rate limiter", priority, waited)
+		reportSaturation("slow_admission")
+	}
 
 	// Forward the CloudEvent to the consuming service
-	return forwardEventToService(ctx, event)
+	result := forwardEventToService(ctx, event, received)
+	if !cloudevents.IsACK(result) {
+		// The event is in the dead-letter sink now, like expired ones it is ACKed so that
+		// the load balancer does not deliver it a second time next to its replay
+		metrics.IncEventsDropped(priority, "forward_failed")
+		return cloudevents.ResultACK
+	}
+	metrics.ObserveEventLatency(priority, time.Since(received))
+	return result
 }
 
-// forwardEventToService forwards the CloudEvent to the configured service URL.
-func forwardEventToService(ctx context.Context, event cloudevents.Event) cloudevents.Result {
-	// Use CloudEvents client to handle the forwarding instead of manually creating the HTTP request
-	client, err := cloudevents.NewClientHTTP()
+// Helper function to read the priority class of an event, falling back to DEFAULT_PRIORITY
+// when the extension is missing or holds an unknown value
+func priorityOf(event cloudevents.Event) string {
+	value, ok := event.Extensions()[config.PriorityExtension]
+	if !ok {
+		return config.DefaultPriority
+	}
+	priority, err := types.ToString(value)
 	if err != nil {
-		log.Printf("❌ Error creating CloudEvents client: %v", err)
-		return cloudevents.NewHTTPResult(http.StatusInternalServerError, "Error creating CloudEvents client")
+		return config.DefaultPriority
 	}
 
+	switch priority = strings.ToLower(priority); priority {
+	case controller.PriorityHigh, controller.PriorityNormal, controller.PriorityLow:
+		return priority
+	default:
+		return config.DefaultPriority
+	}
+}
+
+// forwardEventToService forwards the CloudEvent to the configured service URL using the pooled client.
+// Events the service does not accept are written to the dead-letter sink.
+func forwardEventToService(ctx context.Context, event cloudevents.Event, received time.Time) cloudevents.Result {
+	ctx, cancel := context.WithTimeout(ctx, config.ForwardTimeout)
+	defer cancel()
+
 	// Forward the event
-	ctx = cloudevents.ContextWithTarget(ctx, config.ServiceURL)
-	result := client.Send(ctx, event)
+	result := forwardClient.Send(ctx, event)
 
 	if cloudevents.IsACK(result) {
 		log.Printf("✅ Successfully forwarded CloudEvent to %s", config.ServiceURL)
 		return cloudevents.ResultACK
 	}
 
-	log.Printf("❌ Failed to forward CloudEvent to %s", config.ServiceURL)
+	log.Printf("❌ Failed to forward CloudEvent to %s: %v", config.ServiceURL, result)
+	deadletter.Write(deadletter.Record{
+		Event:       event,
+		Stage:       "rate-controller",
+		Destination: config.ServiceName,
+		Attempts:    1,
+		LastError:   result.Error(),
+		ReceivedAt:  received,
+		FailedAt:    time.Now(),
+	})
 	return result
 }
 
-// Initialize the rate controller
+// Initialize the rate controller and the pooled client used to forward events
 func InitRateController() {
 	rateController = controller.NewRateController()
+
+	client, err := newForwardClient()
+	if err != nil {
+		log.Fatalf("❌ Failed to create forwarding client: %v", err)
+	}
+	forwardClient = client
 }
 
 // StartReceiver initializes the CloudEvents receiver and subscribes to admission rate updates.
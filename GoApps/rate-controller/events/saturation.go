@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"rate-controller/config"
+	"rate-controller/metrics"
+)
+
+var (
+	saturationMu         sync.Mutex
+	lastSaturationReport time.Time
+)
+
+// SaturationMessage is published on saturation:<ThisService> when events queue up in
+// front of the rate limiter, so the load balancer decreases the admission rate of this
+// service only
+type SaturationMessage struct {
+	Service string    `json:"service"`
+	Reason  string    `json:"reason"`
+	At      time.Time `json:"at"`
+}
+
+// Helper function to report saturation to the load balancer, at most once every
+// SATURATION_REPORT_INTERVAL
+func reportSaturation(reason string) {
+	now := time.Now()
+	saturationMu.Lock()
+	if !lastSaturationReport.IsZero() && now.Sub(lastSaturationReport) < config.SaturationReportInterval {
+		saturationMu.Unlock()
+		return
+	}
+	lastSaturationReport = now
+	saturationMu.Unlock()
+
+	payload, err := json.Marshal(SaturationMessage{Service: config.ThisService, Reason: reason, At: now})
+	if err != nil {
+		log.Printf("⚠️ Error encoding saturation report: %v", err)
+		return
+	}
+
+	channel := "saturation:" + config.ThisService
+	if err := rdbClient.Publish(context.Background(), channel, payload).Err(); err != nil {
+		log.Printf("⚠️ Error publishing saturation report on %s: %v", channel, err)
+		return
+	}
+	log.Printf("📢 Reported saturation of %s (%s)", config.ThisService, reason)
+	metrics.IncSaturationReports(reason)
+}
@@ -6,6 +6,8 @@ require (
 	github.com/cloudevents/sdk-go/v2 v2.15.2
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/prometheus/client_golang v1.19.1
+	github.com/streadway/amqp v1.1.0
+	golang.org/x/net v0.23.0
 	golang.org/x/time v0.0.0-20210723032227-1f47c861a9ac
 )
 
@@ -23,6 +25,7 @@ require (
 	go.uber.org/atomic v1.4.0 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
 	go.uber.org/zap v1.10.0 // indirect
-	golang.org/x/sys v0.17.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/protobuf v1.33.0 // indirect
 )
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"log"
+
 	"rate-controller/config"
+	"rate-controller/deadletter"
 	"rate-controller/events"
 	"rate-controller/metrics"
 )
@@ -9,6 +12,11 @@ import (
 func main() {
 	config.LoadConfig()
 
+	// Open the sink for events that cannot be forwarded
+	if err := deadletter.Init(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
 	// Initialize Rate Controller
 	events.InitRateController()
 
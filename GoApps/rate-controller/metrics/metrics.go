@@ -3,6 +3,7 @@ package metrics
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -14,6 +15,27 @@ var (
 		Name: "admission_rate",
 		Help: "Current admission rate",
 	})
+
+	EventLatencyMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "event_latency_seconds",
+		Help:    "Time from receiving an event to its forwarding to the service, by priority.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"priority"})
+
+	EventsDroppedMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_dropped_total",
+		Help: "Number of events that were not forwarded, by priority and reason.",
+	}, []string{"priority", "reason"})
+
+	EventsExpiredMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_expired_total",
+		Help: "Number of events discarded because their deadline passed, by stage.",
+	}, []string{"stage"})
+
+	SaturationReportsMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "saturation_reports_total",
+		Help: "Number of saturation reports published to the load balancer, by reason.",
+	}, []string{"reason"})
 )
 
 func StartMetricsServer() {
@@ -38,3 +60,19 @@ func UpdateMetric(value float64) {
 	AdmissionRateMetric.Set(value)
 	log.Printf("Updated admission rate metric to %f", value)
 }
+
+func ObserveEventLatency(priority string, latency time.Duration) {
+	EventLatencyMetric.WithLabelValues(priority).Observe(latency.Seconds())
+}
+
+func IncEventsDropped(priority, reason string) {
+	EventsDroppedMetric.WithLabelValues(priority, reason).Inc()
+}
+
+func IncEventsExpired(stage string) {
+	EventsExpiredMetric.WithLabelValues(stage).Inc()
+}
+
+func IncSaturationReports(reason string) {
+	SaturationReportsMetric.WithLabelValues(reason).Inc()
+}